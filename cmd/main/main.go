@@ -18,8 +18,14 @@ func main() {
 	manager.EnsureDirsExist()
 	// Setup Database
 	db.Init()
+	manager.StartSessionSweeper()
+	manager.StartLoginFailureSweeper()
 	manager.InitDockerSystem()
 	defer manager.Close()
 	manager.InitMCServerManagement()
+	// the scheduler runs commands on the containers, so the docker client has to be ready
+	manager.StartBackupScheduler()
+	// the purger frees the ports of deleted servers, so the ports of the existing containers have to be known first
+	manager.StartTrashPurger()
 	router.HandleHttpRequests()
 }
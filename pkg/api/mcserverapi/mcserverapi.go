@@ -2,19 +2,25 @@ package mcserverapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/instantmc/server/pkg/models"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const authHeader = "auth"
 
+// statusTimeout prevents that a stuck mc server blocks the caller
+const statusTimeout = 5 * time.Second
+
 func GetServerStatus(port int, authKey string) (models.ServerStatus, error) {
-	client := &http.Client{}
+	client := &http.Client{Timeout: statusTimeout}
 	url := fmt.Sprintf("http://localhost:%d", port)
 	req, _ := http.NewRequest("GET", url, nil)
 	req.Header.Set(authHeader, authKey)
@@ -104,3 +110,60 @@ func SendMessage(port int, authKey string, message string) error {
 	}
 	return nil
 }
+
+var (
+	ErrConsoleClosed  = errors.New("the console has been closed")
+	ErrCommandTimeout = errors.New("the console command didn't complete in time")
+)
+
+// ExecuteCommand Writes the command to the console of the mc server and returns the console output following it
+// The mc server reads its console from stdin, lines are the log lines written to the console from now on
+// The output is complete once the console has been quiet for idleTime. If expectedOutput isn't empty, it's complete once a line
+// contains expectedOutput instead, then ErrCommandTimeout is returned after timeout and ErrConsoleClosed if lines is closed before
+func ExecuteCommand(stdin io.Writer, lines <-chan string, command string, expectedOutput string, idleTime time.Duration, timeout time.Duration) (string, error) {
+	if _, err := stdin.Write([]byte(strings.TrimPrefix(command, "/") + "\n")); err != nil {
+		return "", err
+	}
+	return collectCommandOutput(lines, expectedOutput, idleTime, timeout)
+}
+
+// collectCommandOutput Reads the lines logged after a command until the console is quiet for idleTime or a line contains expectedOutput
+func collectCommandOutput(lines <-chan string, expectedOutput string, idleTime time.Duration, timeout time.Duration) (string, error) {
+	var output []string
+	idle := time.NewTimer(idleTime)
+	defer idle.Stop()
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				// the container stopped
+				if expectedOutput != "" {
+					return strings.Join(output, "\n"), ErrConsoleClosed
+				}
+				return strings.Join(output, "\n"), nil
+			}
+			output = append(output, line)
+			if expectedOutput != "" && strings.Contains(line, expectedOutput) {
+				return strings.Join(output, "\n"), nil
+			}
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(idleTime)
+		case <-idle.C:
+			if expectedOutput == "" {
+				return strings.Join(output, "\n"), nil
+			}
+		case <-deadline.C:
+			if expectedOutput == "" {
+				return strings.Join(output, "\n"), nil
+			}
+			return strings.Join(output, "\n"), ErrCommandTimeout
+		}
+	}
+}
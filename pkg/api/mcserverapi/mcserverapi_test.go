@@ -0,0 +1,54 @@
+package mcserverapi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommand(t *testing.T) {
+	var stdin bytes.Buffer
+	lines := make(chan string, 1)
+	lines <- "[Server thread/INFO]: Set the time to 1000"
+	output, err := ExecuteCommand(&stdin, lines, "/time set day", "", 10*time.Millisecond, time.Second)
+	if stdin.String() != "time set day\n" {
+		t.Errorf("Expected the command without slash on stdin, got %q", stdin.String())
+	}
+	if err != nil || output != "[Server thread/INFO]: Set the time to 1000" {
+		t.Errorf("Expected the output of the command, got %q %v", output, err)
+	}
+}
+
+func TestCollectCommandOutput(t *testing.T) {
+	lines := make(chan string, 3)
+	lines <- "[Server thread/INFO]: There are 0 of a max of 20 players online:"
+	lines <- "[Server thread/INFO]: second line"
+	output, err := collectCommandOutput(lines, "", 20*time.Millisecond, time.Second)
+	if err != nil || output != "[Server thread/INFO]: There are 0 of a max of 20 players online:\n[Server thread/INFO]: second line" {
+		t.Errorf("Expected both lines once the console is quiet, got %q %v", output, err)
+	}
+
+	// the expected output may take longer than the idle time
+	lines = make(chan string)
+	go func() {
+		lines <- "Saving the game (this may take a moment!)"
+		time.Sleep(50 * time.Millisecond)
+		lines <- "Saved the game"
+	}()
+	output, err = collectCommandOutput(lines, "Saved the game", 10*time.Millisecond, time.Second)
+	if err != nil || output != "Saving the game (this may take a moment!)\nSaved the game" {
+		t.Errorf("Expected the output until the expected line, got %q %v", output, err)
+	}
+
+	_, err = collectCommandOutput(make(chan string), "Saved the game", 10*time.Millisecond, 30*time.Millisecond)
+	if !errors.Is(err, ErrCommandTimeout) {
+		t.Errorf("Expected ErrCommandTimeout without the expected line, got %v", err)
+	}
+
+	lines = make(chan string)
+	close(lines)
+	if _, err := collectCommandOutput(lines, "Saved the game", time.Second, time.Second); !errors.Is(err, ErrConsoleClosed) {
+		t.Errorf("Expected ErrConsoleClosed if the container stops, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package mojangapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"net/http"
+	"net/url"
+)
+
+var ErrPlayerNotFound = errors.New("player not found")
+
+type Profile struct {
+	// ID is the uuid without dashes
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetProfile Resolves the uuid and the correctly capitalized name of a player
+// Returns ErrPlayerNotFound if no account has the name
+func GetProfile(name string) (Profile, error) {
+	client := &http.Client{Timeout: config.MojangAPITimeout}
+	resp, err := client.Get(fmt.Sprintf(config.MojangProfileURL, url.PathEscape(name)))
+	if err != nil {
+		return Profile{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotFound {
+		return Profile{}, ErrPlayerNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Profile{}, fmt.Errorf("mojang api responded with status %d", resp.StatusCode)
+	}
+	var profile Profile
+	err = json.NewDecoder(resp.Body).Decode(&profile)
+	return profile, err
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"golang.org/x/exp/slices"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func getAPITokens(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+	apiTokens, err := db.GetAPITokensOfUser(&user)
+	if err != nil {
+		sendError("Couldn't fetch API tokens", w, http.StatusInternalServerError)
+		return
+	}
+
+	tokenData := []models.ClientAPIToken{}
+	for _, apiToken := range apiTokens {
+		tokenData = append(tokenData, apiToken.ToClientJson())
+	}
+
+	sendJSON(w, http.StatusOK, apiTokensResponse{Tokens: tokenData})
+}
+
+func createAPIToken(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	if name == "" {
+		sendError("Please provide the field \"name\"", w, http.StatusBadRequest)
+		return
+	}
+	// comma separated or one value per scope
+	scopesRaw := strings.Join(r.Form["scopes"], ",")
+	if scopesRaw == "" {
+		sendError("Please provide the field \"scopes\"", w, http.StatusBadRequest)
+		return
+	}
+	var scopes []string
+	for _, scope := range strings.Split(scopesRaw, ",") {
+		scope = strings.TrimSpace(scope)
+		if !slices.Contains(enums.AllAPIScopes, enums.APIScope(scope)) {
+			sendError(fmt.Sprintf("Unknown scope %s", scope), w, http.StatusBadRequest)
+			return
+		}
+		scopes = append(scopes, scope)
+	}
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+
+	apiToken := models.APIToken{
+		Name:     name,
+		UserID:   int(user.ID),
+		Scopes:   strings.Join(scopes, ","),
+		ServerID: r.FormValue("server_id"), // Optional
+	}
+	if apiToken.ServerID != "" {
+		serverData, err := db.GetMcServerData(apiToken.ServerID)
+		if err != nil || !canAccessServer(&user, &serverData) {
+			sendError("Server with given ID doesn't exist", w, http.StatusNotFound)
+			return
+		}
+	}
+	if expiresInDaysRaw := r.FormValue("expires_in_days"); expiresInDaysRaw != "" { // Optional
+		expiresInDays, err := strconv.Atoi(expiresInDaysRaw)
+		if err != nil || expiresInDays <= 0 {
+			sendError("Couldn't parse field \"expires_in_days\"", w, http.StatusBadRequest)
+			return
+		}
+		apiToken.ExpiresAt = time.Now().Add(time.Duration(expiresInDays) * 24 * time.Hour)
+	}
+
+	token, err := db.CreateAPIToken(&apiToken)
+	if err != nil {
+		sendError("Couldn't create API token", w, http.StatusInternalServerError)
+		return
+	}
+
+	// the token can't be retrieved later, only its hash is saved
+	sendJSON(w, http.StatusOK, createdAPITokenResponse{Token: token, Details: apiToken.ToClientJson()})
+}
+
+func deleteAPIToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := strconv.Atoi(mux.Vars(r)["tokenid"])
+	if err != nil {
+		sendError("Invalid token ID", w, http.StatusBadRequest)
+		return
+	}
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteAPIToken(&user, uint(tokenID)); err != nil {
+		sendError("API token with given ID doesn't exist", w, http.StatusNotFound)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
@@ -0,0 +1,113 @@
+package router
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+	"net/http"
+)
+
+type auditContextKey struct{}
+
+// auditedMethods are the http methods of mutating requests
+var auditedMethods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// statusRecorder remembers the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// auditMiddleware Saves an audit entry for every mutating request, including requests rejected by the v2Middleware or the authMiddleware
+func auditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(auditedMethods, r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the actor is resolved before the handler runs, e.g. a logout revokes the session
+		entry := newAuditEntry(r, r.Method)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		// the v2Middleware decodes json bodies into the form of this request
+		auditedRequest := r.WithContext(context.WithValue(r.Context(), auditContextKey{}, entry))
+		next.ServeHTTP(recorder, auditedRequest)
+
+		if entry.UserID == 0 && entry.Username == "" {
+			// unauthenticated requests like logins are attributed to the username they name
+			entry.Username = auditedRequest.PostFormValue("username")
+		}
+		entry.Status = recorder.status
+		entry.Success = recorder.status < http.StatusBadRequest
+		if err := db.AddAuditEntry(entry); err != nil {
+			log.Error().Err(err).Msgf("Couldn't save audit entry for %s %s", entry.Method, entry.Route)
+		}
+	})
+}
+
+// auditAction Saves an audit entry for an action within a request, e.g. a command sent over a websocket
+func auditAction(r *http.Request, action string, details string, success bool) {
+	entry := newAuditEntry(r, action)
+	entry.Details = details
+	entry.Success = success
+	if err := db.AddAuditEntry(entry); err != nil {
+		log.Error().Err(err).Msgf("Couldn't save audit entry for %s %s", entry.Method, entry.Route)
+	}
+}
+
+// newAuditEntry Creates an audit entry for the route and the user of the request
+func newAuditEntry(r *http.Request, method string) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		ClientIP: getClientIP(r),
+		Method:   method,
+		Route:    r.URL.Path,
+		ServerID: mux.Vars(r)["serverid"],
+	}
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			entry.Route = template
+		}
+	}
+	authKey := r.Header.Get("auth")
+	if isAPIToken(authKey) {
+		if apiToken, err := db.GetAPIToken(authKey); err == nil {
+			entry.UserID = apiToken.User.ID
+			entry.Username = apiToken.User.Username
+			entry.APITokenID = apiToken.ID
+		}
+	} else if authKey != "" {
+		if session, err := db.GetSession(authKey); err == nil {
+			entry.UserID = session.User.ID
+			entry.Username = session.User.Username
+			entry.SessionID = session.ID
+		}
+	}
+	return entry
+}
+
+// setAuditServerID Sets the target server of the audit entry for routes without the `serverid` route variable
+func setAuditServerID(r *http.Request, serverID string) {
+	if entry, ok := r.Context().Value(auditContextKey{}).(*models.AuditEntry); ok {
+		entry.ServerID = serverID
+	}
+}
+
+// setAuditDetails Sets the details of the audit entry of the request, e.g. the command run by the user
+func setAuditDetails(r *http.Request, details string) {
+	if entry, ok := r.Context().Value(auditContextKey{}).(*models.AuditEntry); ok {
+		entry.Details = details
+	}
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"encoding/json"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/models"
+	"net/http"
+	"time"
+)
+
+// getAuditLog Returns the audit entries filtered by `user`, `server`, `since` and `until`
+// With `format=ndjson` every entry is written as a single json line
+func getAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter := models.AuditFilter{
+		Username: r.FormValue("user"),
+		ServerID: r.FormValue("server"),
+	}
+	for field, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		raw := r.FormValue(field)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			sendError("Couldn't parse field \""+field+"\", expected RFC 3339 format", w, http.StatusBadRequest)
+			return
+		}
+		*target = parsed
+	}
+
+	entries, err := db.GetAuditEntries(filter)
+	if err != nil {
+		sendError("Couldn't fetch audit log", w, http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("format") == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"audit.ndjson\"")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry.ToClientJson()); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	entryData := []models.ClientAuditEntry{}
+	for _, entry := range entries {
+		entryData = append(entryData, entry.ToClientJson())
+	}
+	sendJSON(w, http.StatusOK, auditLogResponse{Entries: entryData})
+}
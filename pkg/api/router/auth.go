@@ -1,13 +1,60 @@
 package router
 
 import (
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/config"
 	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
 	"github.com/instantmc/server/pkg/models"
+	"net"
 	"net/http"
+	"strings"
 )
 
 func getCurrentUser(r *http.Request) (models.User, error) {
 	clientAuthKey := r.Header.Get("auth")
+	if isAPIToken(clientAuthKey) {
+		apiToken, err := db.GetAPIToken(clientAuthKey)
+		return apiToken.User, err
+	}
 	// searching for session...
 	return db.GetUserFromToken(clientAuthKey)
 }
+
+func isAPIToken(token string) bool {
+	return strings.HasPrefix(token, config.APITokenPrefix)
+}
+
+// getCurrentSession Returns the session of the auth header
+func getCurrentSession(r *http.Request) (models.Session, error) {
+	return db.GetSession(r.Header.Get("auth"))
+}
+
+// getClientIP Returns the ip address of the client without the port
+func getClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getAccessibleServer Returns the server of the `serverid` route variable if the current user owns it or is an admin
+// Otherwise an error response is sent and false is returned. Servers of other users are reported as not found
+func getAccessibleServer(w http.ResponseWriter, r *http.Request) (models.DBMcServerContainer, models.User, bool) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return models.DBMcServerContainer{}, user, false
+	}
+	serverData, err := db.GetMcServerData(mux.Vars(r)["serverid"])
+	if err != nil || !canAccessServer(&user, &serverData) {
+		sendError("Server with given ID doesn't exist", w, http.StatusNotFound)
+		return models.DBMcServerContainer{}, user, false
+	}
+	return serverData, user, true
+}
+
+func canAccessServer(user *models.User, server *models.DBMcServerContainer) bool {
+	return user.Role == enums.Admin || server.IsOwnedBy(user)
+}
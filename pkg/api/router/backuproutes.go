@@ -0,0 +1,210 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strconv"
+)
+
+// getBackups Lists the backups of the server, newest first, and its backup schedule (null if there is none)
+func getBackups(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	backups, err := db.GetBackups(mcServerData.ServerID)
+	if err != nil {
+		sendError("Couldn't fetch backups", w, http.StatusInternalServerError)
+		return
+	}
+	response := backupsResponse{Backups: []models.ClientBackup{}, Retention: manager.GetBackupRetention(mcServerData.ServerID)}
+	for _, backup := range backups {
+		response.Backups = append(response.Backups, backup.ToClientJson())
+	}
+	if schedule, err := db.GetBackupSchedule(mcServerData.ServerID); err == nil {
+		clientSchedule := schedule.ToClientJson()
+		response.Schedule = &clientSchedule
+	}
+	sendJSON(w, http.StatusOK, response)
+}
+
+// createBackup Archives the world of the server, the request blocks until the archive is complete
+func createBackup(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	backup, err := manager.CreateBackup(&mcServerData, user.ID, false)
+	if errors.Is(err, manager.ErrWorldBusy) {
+		sendError("A backup or restore of the server is already in progress", w, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Couldn't create backup of server %s", mcServerData.ServerID)
+		sendError("Couldn't create backup", w, http.StatusInternalServerError)
+		return
+	}
+	setAuditDetails(r, backup.FileName)
+	sendJSON(w, http.StatusOK, backup.ToClientJson())
+}
+
+func deleteBackup(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	backup, ok := getRequestedBackup(w, r, mcServerData.ServerID)
+	if !ok {
+		return
+	}
+	setAuditDetails(r, backup.FileName)
+	if err := manager.DeleteBackup(&backup); err != nil {
+		log.Error().Err(err).Msgf("Couldn't delete backup %d of server %s", backup.ID, mcServerData.ServerID)
+		sendError("Couldn't delete backup", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+// getRequestedBackup Responds with 404 if the server has no backup with the ID of the `backupid` route variable
+func getRequestedBackup(w http.ResponseWriter, r *http.Request, serverID string) (models.DBBackup, bool) {
+	backupID, err := strconv.ParseUint(mux.Vars(r)["backupid"], 10, 32)
+	if err != nil {
+		sendError("Backup doesn't exist", w, http.StatusNotFound)
+		return models.DBBackup{}, false
+	}
+	backup, err := manager.GetBackup(serverID, uint(backupID))
+	if errors.Is(err, manager.ErrBackupNotFound) {
+		sendError("Backup doesn't exist", w, http.StatusNotFound)
+		return backup, false
+	} else if err != nil {
+		sendError("Couldn't fetch backup", w, http.StatusInternalServerError)
+		return backup, false
+	}
+	return backup, true
+}
+
+// setBackupSchedule Creates or replaces the backup schedule of the server
+// Omitted retention rules use the defaults of the config
+func setBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	cron := r.FormValue("cron")
+	if cron == "" {
+		sendError("Please provide the field \"cron\"", w, http.StatusBadRequest)
+		return
+	}
+	retention := models.BackupRetention{
+		KeepLast:   config.BackupDefaultKeepLast,
+		KeepDaily:  config.BackupDefaultKeepDaily,
+		KeepWeekly: config.BackupDefaultKeepWeekly,
+	}
+	for field, target := range map[string]*int{"keep_last": &retention.KeepLast, "keep_daily": &retention.KeepDaily, "keep_weekly": &retention.KeepWeekly} {
+		if raw := r.FormValue(field); raw != "" { // Optional
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				sendError(fmt.Sprintf("Couldn't parse field \"%s\"", field), w, http.StatusBadRequest)
+				return
+			}
+			*target = value
+		}
+	}
+	setAuditDetails(r, cron)
+
+	schedule, err := manager.SetBackupSchedule(mcServerData.ServerID, cron, retention)
+	if errors.Is(err, manager.ErrInvalidBackupSchedule) {
+		sendError(err.Error(), w, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Couldn't save backup schedule of server %s", mcServerData.ServerID)
+		sendError("Couldn't save backup schedule", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, schedule.ToClientJson())
+}
+
+// deleteBackupSchedule Stops the scheduled backups of the server, existing backups are kept
+func deleteBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	if err := db.DeleteBackupSchedule(mcServerData.ServerID); err != nil {
+		sendError("Couldn't delete backup schedule", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+// restoreServer Replaces the world of the server with a backup of any accessible server
+// A running server is stopped for the restore and started again afterwards
+func restoreServer(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	rawBackupID := r.FormValue("backup_id")
+	if rawBackupID == "" {
+		sendError("Please provide the field \"backup_id\"", w, http.StatusBadRequest)
+		return
+	}
+	backup, ok := getAccessibleBackup(w, &user, rawBackupID)
+	if !ok {
+		return
+	}
+	setAuditDetails(r, fmt.Sprintf("backup %d of server %s", backup.ID, backup.ServerID))
+
+	if err := manager.RestoreBackup(&mcServerData, &backup, user.ID); err != nil {
+		log.Error().Err(err).Msgf("Couldn't restore backup %d into server %s", backup.ID, mcServerData.ServerID)
+		sendRestoreError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, mcServerData.ToClientJson())
+}
+
+// getAccessibleBackup Responds with 400 if the backup doesn't exist or the user can't access its server
+// Only admins can access the backups of deleted servers
+func getAccessibleBackup(w http.ResponseWriter, user *models.User, rawBackupID string) (models.DBBackup, bool) {
+	backupID, err := strconv.ParseUint(rawBackupID, 10, 32)
+	if err != nil {
+		sendError(fmt.Sprintf("Backup %s doesn't exist", rawBackupID), w, http.StatusBadRequest)
+		return models.DBBackup{}, false
+	}
+	backup, err := manager.GetBackupByID(uint(backupID))
+	if err != nil && !errors.Is(err, manager.ErrBackupNotFound) {
+		sendError("Couldn't fetch backup", w, http.StatusInternalServerError)
+		return backup, false
+	}
+	accessible := err == nil && user.Role == enums.Admin
+	if err == nil && !accessible {
+		server, err := db.GetMcServerData(backup.ServerID)
+		accessible = err == nil && canAccessServer(user, &server)
+	}
+	if !accessible {
+		sendError(fmt.Sprintf("Backup %s doesn't exist", rawBackupID), w, http.StatusBadRequest)
+		return backup, false
+	}
+	return backup, true
+}
+
+// sendRestoreError Responds with 400 if the archive can't be restored, with 409 if the server state doesn't allow the restore
+func sendRestoreError(err error, w http.ResponseWriter) {
+	if errors.Is(err, manager.ErrNewerWorldVersion) || errors.Is(err, manager.ErrInvalidWorldArchive) {
+		sendError(err.Error(), w, http.StatusBadRequest)
+	} else if errors.Is(err, manager.ErrWorldBusy) {
+		sendError("A backup or restore of the server is already in progress", w, http.StatusConflict)
+	} else if errors.Is(err, manager.ErrInvalidStateTransition) {
+		sendStateTransitionError(err, w)
+	} else {
+		sendError("Couldn't restore backup", w, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+)
+
+// serverCommand Runs a command in the console of the mc server and returns its output
+// The commands a user may run depend on the role, see config.CommandAllowlist and config.CommandDenylist
+func serverCommand(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	command := strings.TrimSpace(r.FormValue("command"))
+	setAuditDetails(r, command)
+	output, status, err := runServerCommand(&mcServerData, &user, command)
+	if err != nil {
+		sendError(err.Error(), w, status)
+		return
+	}
+	sendJSON(w, http.StatusOK, commandResponse{Command: command, Output: output})
+}
+
+// runServerCommand Checks the command against the policy of the role of the user and executes it
+// Returns the http status and a client facing error if the command couldn't be run
+func runServerCommand(server *models.DBMcServerContainer, user *models.User, command string) (string, int, error) {
+	if command == "" || len(command) > config.ConsoleMaximumCommandLength {
+		return "", http.StatusBadRequest, fmt.Errorf("Commands must have 1 to %d characters", config.ConsoleMaximumCommandLength)
+	}
+	if err := manager.CheckCommandAllowed(user.Role, command); err != nil {
+		return "", http.StatusForbidden, errors.New("Insufficient permissions to run this command")
+	}
+	output, err := manager.ExecuteMcServerCommand(server, command)
+	if errors.Is(err, manager.ErrInvalidCommand) {
+		return "", http.StatusBadRequest, errors.New("Commands must be a single line")
+	}
+	if errors.Is(err, manager.ErrServerNotRunning) {
+		return "", http.StatusConflict, errors.New("Server is not running")
+	}
+	if err != nil {
+		log.Warn().Err(err).Msgf("Couldn't execute command on server %s", server.ServerID)
+		return "", http.StatusBadGateway, errors.New("Couldn't execute command")
+	}
+	return output, http.StatusOK, nil
+}
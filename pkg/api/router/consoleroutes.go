@@ -0,0 +1,107 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// consoleConnection serializes the writes to the websocket, the log stream and the command results are sent concurrently
+type consoleConnection struct {
+	conn  *websocket.Conn
+	mutex sync.Mutex
+}
+
+func (console *consoleConnection) send(message consoleMessage) error {
+	console.mutex.Lock()
+	defer console.mutex.Unlock()
+	return console.conn.WriteJSON(message)
+}
+
+// serverConsole Streams the log of the mc server and runs the commands sent by the client
+// The last `lines` log lines are replayed on connect. The commands a user may send depend on the role
+func serverConsole(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	if mcServerData.ContainerID == "" {
+		sendError("Server has no container, start it first", w, http.StatusConflict)
+		return
+	}
+	replayLines := config.ConsoleReplayLines
+	if replayLinesRaw := r.FormValue("lines"); replayLinesRaw != "" { // Optional
+		var err error
+		replayLines, err = strconv.Atoi(replayLinesRaw)
+		if err != nil || replayLines < 0 || replayLines > config.ConsoleMaximumReplayLines {
+			sendError(fmt.Sprintf("\"lines\" must be between 0 and %d", config.ConsoleMaximumReplayLines), w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	conn, err := Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		sendError("Couldn't establish a websocket connection", w, http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+	console := &consoleConnection{conn: conn}
+
+	streamCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		// the client closing the connection ends the log stream
+		defer cancel()
+		for {
+			var command consoleCommand
+			if err := conn.ReadJSON(&command); err != nil {
+				var closeError *websocket.CloseError
+				if !errors.As(err, &closeError) {
+					log.Debug().Err(err).Msgf("Console connection of server %s closed", mcServerData.ServerID)
+				}
+				return
+			}
+			command.Command = strings.TrimSpace(command.Command)
+			// the server may have been stopped or restarted since the connection has been established
+			currentServerData, err := db.GetMcServerData(mcServerData.ServerID)
+			if err != nil {
+				console.send(consoleMessage{Type: "error", Command: command.Command, Message: "Server doesn't exist any more"})
+				continue
+			}
+			output, status, err := runServerCommand(&currentServerData, &user, command.Command)
+			if status != http.StatusBadRequest {
+				auditAction(r, "CONSOLE", command.Command, err == nil)
+			}
+			if err != nil {
+				console.send(consoleMessage{Type: "error", Command: command.Command, Message: err.Error()})
+				continue
+			}
+			console.send(consoleMessage{Type: "output", Command: command.Command, Output: output})
+		}
+	}()
+
+	lines := make(chan string)
+	go func() {
+		if err := manager.FollowContainerLogs(streamCtx, mcServerData.ContainerID, replayLines, lines); err != nil && streamCtx.Err() == nil {
+			log.Warn().Err(err).Msgf("Couldn't follow log of server %s", mcServerData.ServerID)
+		}
+		close(lines)
+	}()
+	for line := range lines {
+		if err := console.send(consoleMessage{Type: "log", Line: line}); err != nil {
+			cancel()
+		}
+	}
+	// the container stopped or the client disconnected
+	console.send(consoleMessage{Type: "closed"})
+}
@@ -0,0 +1,137 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+// listServerFiles Lists a directory in the world directory of the server, the query parameter `path` defaults to the world directory
+func listServerFiles(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	files, err := manager.ListServerFiles(mcServerData.Port, r.FormValue("path"))
+	if err != nil {
+		sendFileError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, serverFilesResponse{Path: r.FormValue("path"), Files: files})
+}
+
+// readServerFile Responds with the raw content of a file, it's always sent as download so the browser doesn't render it
+func readServerFile(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	file, info, err := manager.OpenServerFile(mcServerData.Port, r.FormValue("path"))
+	if err != nil {
+		sendFileError(err, w)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", strconv.Quote(path.Base(info.Name()))))
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.CopyN(w, file, info.Size()); err != nil {
+		log.Warn().Err(err).Msgf("Couldn't send file of server %s", mcServerData.ServerID)
+	}
+}
+
+// writeServerFile Creates or replaces a file with the request body
+func writeServerFile(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	// r.FormValue would consume a form encoded body
+	filePath := r.URL.Query().Get("path")
+	setAuditDetails(r, filePath)
+	if r.Body == nil {
+		sendError("Please send the file content as request body", w, http.StatusBadRequest)
+		return
+	}
+	if err := manager.WriteServerFile(mcServerData.Port, filePath, r.Body); err != nil {
+		sendFileError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+func makeServerDir(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	dirPath := r.FormValue("path")
+	if dirPath == "" {
+		sendError("Please provide the field \"path\"", w, http.StatusBadRequest)
+		return
+	}
+	setAuditDetails(r, dirPath)
+	if err := manager.MakeServerDir(mcServerData.Port, dirPath); err != nil {
+		sendFileError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+func moveServerFile(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	from, to := r.FormValue("from"), r.FormValue("to")
+	if from == "" || to == "" {
+		sendError("Please provide the fields \"from\" and \"to\"", w, http.StatusBadRequest)
+		return
+	}
+	setAuditDetails(r, fmt.Sprintf("%s -> %s", from, to))
+	if err := manager.MoveServerFile(mcServerData.Port, from, to); err != nil {
+		sendFileError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+// deleteServerFile Deletes a file or a directory including its content
+func deleteServerFile(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	filePath := r.FormValue("path")
+	setAuditDetails(r, filePath)
+	if err := manager.DeleteServerFile(mcServerData.Port, filePath); err != nil {
+		sendFileError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+// sendFileError Responds with 400 for invalid paths, 404 for missing files, 409 for existing files and 413 for too large files
+func sendFileError(err error, w http.ResponseWriter) {
+	switch {
+	case errors.Is(err, manager.ErrInvalidFilePath), errors.Is(err, manager.ErrIsDirectory), errors.Is(err, manager.ErrNotDirectory):
+		sendError(err.Error(), w, http.StatusBadRequest)
+	case errors.Is(err, manager.ErrFileNotFound):
+		sendError("File doesn't exist", w, http.StatusNotFound)
+	case errors.Is(err, manager.ErrFileExists):
+		sendError("File already exists", w, http.StatusConflict)
+	case errors.Is(err, manager.ErrFileTooLarge):
+		sendError(err.Error(), w, http.StatusRequestEntityTooLarge)
+	default:
+		log.Error().Err(err).Msg("File operation failed")
+		sendError("File operation failed", w, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func getLoginFailures(w http.ResponseWriter, r *http.Request) {
+	failures, err := db.GetLoginFailures()
+	if err != nil {
+		sendError("Couldn't fetch failed logins", w, http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	failureData := []models.ClientLoginFailure{}
+	for _, failure := range failures {
+		failureData = append(failureData, failure.ToClientJson(now))
+	}
+
+	sendJSON(w, http.StatusOK, lockoutsResponse{Lockouts: failureData})
+}
+
+func deleteLoginFailure(w http.ResponseWriter, r *http.Request) {
+	failureID, err := strconv.Atoi(mux.Vars(r)["lockoutid"])
+	if err != nil {
+		sendError("Invalid lockout ID", w, http.StatusBadRequest)
+		return
+	}
+	if err := db.DeleteLoginFailure(uint(failureID)); err != nil {
+		sendError("Lockout with given ID doesn't exist", w, http.StatusNotFound)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// serverLogs Searches the archived and the current log of a server
+// The archived logs of a server in the trash can be searched by its owner, admins can also search the ones of purged servers
+func serverLogs(w http.ResponseWriter, r *http.Request) {
+	query, ok := parseLogQuery(w, r)
+	if !ok {
+		return
+	}
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+
+	serverID := mux.Vars(r)["serverid"]
+	var containerID string
+	if serverData, err := db.GetMcServerData(serverID); err == nil {
+		if !canAccessServer(&user, &serverData) {
+			sendError("Server with given ID doesn't exist", w, http.StatusNotFound)
+			return
+		}
+		containerID = serverData.ContainerID
+	} else if deletedServer, err := db.GetDeletedMcServer(serverID); err == nil {
+		// the container of a deleted server has been removed, only its archived logs are left
+		if !canAccessServer(&user, &deletedServer) {
+			sendError("Server with given ID doesn't exist", w, http.StatusNotFound)
+			return
+		}
+	} else if user.Role != enums.Admin || !manager.IsValidServerID(serverID) || !manager.HasArchivedLogs(serverID) {
+		sendError("Server with given ID doesn't exist", w, http.StatusNotFound)
+		return
+	}
+
+	lines, err := manager.GetMcServerLogs(serverID, containerID, query)
+	if err != nil {
+		log.Error().Err(err).Msgf("Couldn't read log of server %s", serverID)
+		sendError("Couldn't read server log", w, http.StatusInternalServerError)
+		return
+	}
+	if lines == nil {
+		lines = []models.LogLine{}
+	}
+	sendJSON(w, http.StatusOK, logsResponse{Lines: lines})
+}
+
+// parseLogQuery Reads the filters `since`, `until`, `grep` and `tail`. Otherwise an error response is sent and false is returned
+func parseLogQuery(w http.ResponseWriter, r *http.Request) (models.LogQuery, bool) {
+	query := models.LogQuery{Tail: config.LogDefaultTail}
+	for field, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		raw := r.FormValue(field)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			sendError("Couldn't parse field \""+field+"\", expected RFC 3339 format", w, http.StatusBadRequest)
+			return query, false
+		}
+		*target = parsed
+	}
+	if grep := r.FormValue("grep"); grep != "" {
+		pattern, err := regexp.Compile(grep)
+		if err != nil {
+			sendError(fmt.Sprintf("Invalid pattern in field \"grep\": %s", err.Error()), w, http.StatusBadRequest)
+			return query, false
+		}
+		query.Grep = pattern
+	}
+	if tailRaw := r.FormValue("tail"); tailRaw != "" {
+		tail, err := strconv.Atoi(tailRaw)
+		if err != nil || tail <= 0 || tail > config.LogMaximumTail {
+			sendError(fmt.Sprintf("\"tail\" must be between 1 and %d", config.LogMaximumTail), w, http.StatusBadRequest)
+			return query, false
+		}
+		query.Tail = tail
+	}
+	return query, true
+}
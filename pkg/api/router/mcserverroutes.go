@@ -1,7 +1,7 @@
 package router
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -34,38 +34,68 @@ func getPreparedServer(w http.ResponseWriter, r *http.Request) {
 		result = append(result, models.PreparedContainer{Number: nr, McVersion: mcVersion, RamSizeMB: ramSize})
 	}
 
-	data, _ := json.Marshal(map[string]interface{}{
-		"prepared_server": result,
-	})
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	sendJSON(w, http.StatusOK, preparedServerResponse{PreparedServer: result})
 }
 
 func getServer(w http.ResponseWriter, r *http.Request) {
-	server, err := manager.GetRunningMcServer()
+	// the saved state of servers whose container died needs to be corrected before listing
+	if err := manager.DetectCrashedMcServer(); err != nil {
+		log.Warn().Err(err).Msg("Couldn't detect crashed mc server")
+	}
+
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+
+	// admins see the server of all users
+	var server []models.DBMcServerContainer
+	if user.Role == enums.Admin {
+		server, err = db.GetSavedMcServer()
+	} else {
+		server, err = db.GetSavedMcServerOfUser(&user)
+	}
 	if err != nil {
-		sendError("Couldn't fetch running mc server", w, http.StatusInternalServerError)
+		sendError("Couldn't fetch mc server", w, http.StatusInternalServerError)
 		return
 	}
 
-	serverData := []interface{}{}
+	serverData := []models.ClientMcServer{}
 	for _, curServer := range server {
 		serverData = append(serverData, curServer.ToClientJson())
 	}
 
-	data, _ := json.Marshal(map[string]interface{}{
-		"server": serverData,
-	})
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	sendJSON(w, http.StatusOK, serverListResponse{Server: serverData})
+}
+
+func getServerDetails(w http.ResponseWriter, r *http.Request) {
+	// a server whose container died has to be reported as crashed
+	if err := manager.DetectCrashedMcServer(); err != nil {
+		log.Warn().Err(err).Msg("Couldn't detect crashed mc server")
+	}
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+
+	details, err := manager.GetMcServerDetails(&mcServerData)
+	if err != nil {
+		log.Error().Err(err).Msgf("Couldn't inspect container of server %s", mcServerData.ServerID)
+		sendError("Couldn't inspect server container", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, details)
 }
 
 func deleteServer(w http.ResponseWriter, r *http.Request) {
-	serverID := mux.Vars(r)["serverid"]
 	// we need to check if the server exists
-	mcServerData, err := db.GetMcServerData(serverID)
-	if err != nil {
-		sendError("Server with given ID doesn't exist", w, http.StatusNotFound)
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	if err := manager.TransitionServerState(&mcServerData, enums.Deleting, user.ID, "Deletion requested"); err != nil {
+		sendStateTransitionError(err, w)
 		return
 	}
 	// stop container if it's running
@@ -88,25 +118,183 @@ func deleteServer(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	// now we need to delete the mc world volume
-	if err := manager.DeleteMcWorld(mcServerData.Port); err != nil {
-		sendError("Couldn't delete mc world", w, http.StatusInternalServerError)
-		log.Error().Err(err).Msgf("Couldn't delete mc world with port %d", mcServerData.Port)
+	// the world is moved into the trash and the port stays reserved, so the server can be undeleted during the grace period
+	if err := manager.SoftDeleteMcServer(&mcServerData, user.ID); errors.Is(err, manager.ErrWorldBusy) {
+		// the server stays in Deleting, the deletion can be requested again
+		sendError("A backup or restore of the server is in progress, please retry the deletion", w, http.StatusConflict)
+		return
+	} else if err != nil {
+		sendError("Couldn't move server to trash", w, http.StatusInternalServerError)
+		log.Error().Err(err).Msgf("Couldn't move server %s to trash", mcServerData.ServerID)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+// getTrash Lists the deleted servers of the user which can be undeleted, admins see all deleted servers
+func getTrash(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+	deletedServer, err := db.GetDeletedMcServers()
+	if err != nil {
+		sendError("Couldn't fetch deleted server", w, http.StatusInternalServerError)
+		return
+	}
+	response := trashResponse{Server: []models.ClientDeletedMcServer{}}
+	for _, server := range deletedServer {
+		if canAccessServer(&user, &server) {
+			response.Server = append(response.Server, models.ClientDeletedMcServer{
+				ClientMcServer: server.ToClientJson(),
+				DeletedAt:      server.DeletedAt.Time,
+				PurgeAt:        manager.GetPurgeTime(&server),
+			})
+		}
+	}
+	sendJSON(w, http.StatusOK, response)
+}
+
+// undeleteServer Moves a deleted server back from the trash, it's stopped afterwards
+func undeleteServer(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+	mcServerData, err := db.GetDeletedMcServer(mux.Vars(r)["serverid"])
+	if err != nil || !canAccessServer(&user, &mcServerData) {
+		sendError("Deleted server with given ID doesn't exist", w, http.StatusNotFound)
+		return
+	}
+	if err := manager.UndeleteMcServer(&mcServerData, user.ID); errors.Is(err, manager.ErrPortInUse) {
+		sendError(fmt.Sprintf("Server can't be undeleted: %s", err.Error()), w, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Couldn't undelete server %s", mcServerData.ServerID)
+		sendStateTransitionError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, mcServerData.ToClientJson())
+}
+
+func stopServer(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	serverID := mcServerData.ServerID
+
+	if err := manager.StopMcServer(&mcServerData, user.ID, "Stop requested"); err != nil {
+		sendStateTransitionError(err, w)
+		log.Error().Err(err).Msgf("Couldn't stop server %s", serverID)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, mcServerData.ToClientJson())
+}
+
+func startStoppedServer(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	serverID := mcServerData.ServerID
+
+	if err := manager.CheckServerStateTransition(mcServerData.Status, enums.Starting); err != nil {
+		sendStateTransitionError(err, w)
+		return
+	}
+	if err := manager.AdmitMcServer(serverID, mcServerData.RamSizeMB); err != nil {
+		sendAdmissionError(err, w)
+		return
+	}
+	// the starting server allocates its ram in the db
+	defer manager.ReleaseMcServerAdmission(serverID)
+
+	if err := manager.StartSavedMcServer(&mcServerData, user.ID, "Start requested"); err != nil {
+		sendStateTransitionError(err, w)
+		log.Error().Err(err).Msgf("Couldn't start server %s", serverID)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, mcServerData.ToClientJson())
+}
+
+func restartServer(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	serverID := mcServerData.ServerID
+
+	if mcServerData.Status == enums.Running {
+		if err := manager.StopMcServer(&mcServerData, user.ID, "Restart requested"); err != nil {
+			sendStateTransitionError(err, w)
+			log.Error().Err(err).Msgf("Couldn't stop server %s", serverID)
+			return
+		}
+	} else {
+		// a stopped or crashed server doesn't allocate its ram yet, it's admitted like by startStoppedServer
+		if err := manager.CheckServerStateTransition(mcServerData.Status, enums.Starting); err != nil {
+			sendStateTransitionError(err, w)
+			return
+		}
+		if err := manager.AdmitMcServer(serverID, mcServerData.RamSizeMB); err != nil {
+			sendAdmissionError(err, w)
+			return
+		}
+		defer manager.ReleaseMcServerAdmission(serverID)
+	}
+	if err := manager.StartSavedMcServer(&mcServerData, user.ID, "Restart requested"); err != nil {
+		sendStateTransitionError(err, w)
+		log.Error().Err(err).Msgf("Couldn't start server %s", serverID)
 		return
 	}
 
-	// we need to make the port available again
-	manager.RemovePortFromUsageList(mcServerData.Port)
+	sendJSON(w, http.StatusOK, mcServerData.ToClientJson())
+}
 
-	// finally we delete the db entry
-	if err := db.DeleteServer(&mcServerData); err != nil {
-		sendError("Couldn't delete server from db", w, http.StatusInternalServerError)
+func serverHistory(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	history, err := db.GetServerStateHistory(mcServerData.ServerID)
+	if err != nil {
+		sendError("Couldn't fetch server history", w, http.StatusInternalServerError)
 		return
 	}
 
-	data, _ := json.Marshal(map[string]interface{}{})
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	historyData := []models.ClientServerStateTransition{}
+	for _, transition := range history {
+		historyData = append(historyData, transition.ToClientJson())
+	}
+
+	sendJSON(w, http.StatusOK, serverHistoryResponse{History: historyData})
+}
+
+// sendAdmissionError Responds with 409 if the host memory is exhausted and with 507 if the disk is full
+func sendAdmissionError(err error, w http.ResponseWriter) {
+	if errors.Is(err, manager.ErrInsufficientMemory) {
+		sendError(fmt.Sprintf("Server can't be started: %s", err.Error()), w, http.StatusConflict)
+	} else if errors.Is(err, manager.ErrInsufficientStorage) {
+		sendError(fmt.Sprintf("Server can't be started: %s", err.Error()), w, http.StatusInsufficientStorage)
+	} else {
+		log.Error().Err(err).Msg("Couldn't check host resources")
+		sendError("Couldn't check host resources", w, http.StatusInternalServerError)
+	}
+}
+
+// sendStateTransitionError Responds with 409 if the server is in a state which doesn't allow the requested action
+func sendStateTransitionError(err error, w http.ResponseWriter) {
+	if errors.Is(err, manager.ErrInvalidStateTransition) {
+		sendError(fmt.Sprintf("Action not possible in the current server state (%s)", err.Error()), w, http.StatusConflict)
+		return
+	}
+	sendError("Couldn't change server state", w, http.StatusInternalServerError)
 }
 
 func startServer(w http.ResponseWriter, r *http.Request) {
@@ -128,8 +316,8 @@ func startServer(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			sendError("Couldn't parse field \"ram\"", w, http.StatusBadRequest)
 			return
-		} else if targetRamSize > config.MaximumRamPerInstance {
-			sendError(fmt.Sprintf("Requested ram exceeds maximum allowed ram size (%dmb)", config.MaximumRamPerInstance), w, http.StatusBadRequest)
+		} else if maximumRam := manager.GetMaximumRamPerInstance(); targetRamSize > maximumRam {
+			sendError(fmt.Sprintf("Requested ram exceeds maximum allowed ram size (%dmb)", maximumRam), w, http.StatusBadRequest)
 			return
 		}
 	}
@@ -141,58 +329,136 @@ func startServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	serverID := manager.GenerateMcServerID(name)
+	// the world generation settings of a named profile (Optional) can be overridden by single settings (Optional)
+	worldProfile, err := manager.ResolveWorldProfile(mcVersion, r.FormValue("profile"), models.WorldGenerationProfile{
+		Seed:      r.FormValue("seed"),
+		LevelType: r.FormValue("level_type"),
+		GameMode:  r.FormValue("gamemode"),
+	})
+	if errors.Is(err, manager.ErrWorldProfileNotFound) {
+		sendError(fmt.Sprintf("World profile %s doesn't exist", r.FormValue("profile")), w, http.StatusBadRequest)
+		return
+	} else if err != nil {
+		sendServerPropertiesError(err, w)
+		return
+	}
 
-	preparationChan := manager.AddPreparingServer(serverID)
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+
+	// the world of a backup (Optional) is used instead of generating a new one
+	var fromBackup *models.DBBackup
+	if rawBackupID := r.FormValue("from_backup"); rawBackupID != "" {
+		if worldProfile.Name != "" || !worldProfile.IsDefault() {
+			sendError("from_backup can't be combined with world generation settings", w, http.StatusBadRequest)
+			return
+		}
+		backup, ok := getAccessibleBackup(w, &user, rawBackupID)
+		if !ok {
+			return
+		}
+		if err := manager.CheckBackupCompatible(&backup, mcVersion); err != nil {
+			sendRestoreError(err, w)
+			return
+		}
+		fromBackup = &backup
+	}
 
-	// Check if a prepared server with requested mc version exists
+	serverID := manager.GenerateMcServerID(name)
+	setAuditServerID(r, serverID)
+
+	// Check if a prepared server with requested mc version and world exists
 	readyContainer, err := manager.GetMcServerContainer(models.McContainerSearchConfig{
-		McVersion: mcVersion,
-		RamSizeMB: targetRamSize,
-		Status:    enums.Prepared,
+		McVersion:    mcVersion,
+		RamSizeMB:    targetRamSize,
+		Status:       enums.Prepared,
+		WorldProfile: worldProfile.Name,
 	})
 	if err != nil {
 		sendError("Couldn't fetch available server", w, http.StatusInternalServerError)
 		return
 	}
-	user, err := getCurrentUser(r)
-	if err != nil {
-		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
-		return
+	if fromBackup != nil {
+		// prepared containers already generated their own world
+		readyContainer = nil
+	} else if worldProfile.CanBePrepared() {
+		manager.RecordStartRequest(mcVersion, targetRamSize, worldProfile.Name, len(readyContainer) > 0, user.ID)
+	} else {
+		// the unnamed custom settings match the prepared default worlds by name only
+		readyContainer = nil
 	}
 
-	if len(readyContainer) > 0 {
+	for _, container := range readyContainer {
 		// no need for preparation, we can start a mc server instance instantly
-		mcServer, err := manager.StartMcServer(readyContainer[0].ID, name)
-		authKey := manager.GetAuthKeyForMcServer(readyContainer[0].ID)
+		mcServer, err := manager.StartMcServer(container.ID, name)
+		if errors.Is(err, manager.ErrPreparedContainerUnavailable) {
+			// consumed by another request or removed by the pool worker in the meantime
+			continue
+		}
+		setAuditServerID(r, mcServer.ServerID)
+		authKey := manager.GetAuthKeyForMcServer(container.ID)
 		mcserverapi.SendMessage(mcServer.Port, authKey, "Server wake up successful")
 		if err != nil {
 			sendError("Couldn't start mc server", w, http.StatusInternalServerError)
 			return
 		}
-		if err := db.AddMcServerContainer(&user, &mcServer); err != nil {
+		// the consumed container needs to be replaced
+		manager.RefillPreparedServerPool()
+		if _, err := manager.RegisterMcServer(&user, &mcServer, "Started from prepared container"); err != nil {
 			sendError("Couldn't add mc server to database", w, http.StatusInternalServerError)
 			return
 		}
 
-		data, _ := json.Marshal(mcServer.ToClientJson())
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
+		sendJSON(w, http.StatusOK, mcServer.ToClientJson())
 		return
-	} else {
-		data, _ := json.Marshal(map[string]interface{}{
-			"status":      enums.Preparing.String(),
-			"server_id":   serverID,
-			"name":        name,
-			"ram_size_mb": targetRamSize,
-			"mc_version":  mcVersion,
-		})
-		w.WriteHeader(http.StatusOK)
-		w.Write(data)
 	}
 
+	if err := manager.AdmitMcServer(serverID, targetRamSize); err != nil {
+		sendAdmissionError(err, w)
+		return
+	}
+	// the registered server allocates its ram in the db
+	defer manager.ReleaseMcServerAdmission(serverID)
+
+	port := manager.GeneratePort()
+	if fromBackup != nil {
+		if err := manager.CreateMcWorldFromBackup(fromBackup, port); err != nil {
+			log.Error().Err(err).Msgf("Couldn't create world of server %s from backup %d", serverID, fromBackup.ID)
+			manager.RemovePortFromUsageList(port)
+			sendRestoreError(err, w)
+			return
+		}
+	}
+	mcServer := models.McServerContainer{
+		ServerID:  serverID,
+		Name:      name,
+		McVersion: mcVersion,
+		RamSizeMB: targetRamSize,
+		Port:      port,
+		Status:    enums.Preparing,
+	}
+	preparationChan := manager.AddPreparingServer(serverID)
+	dbServer, err := manager.RegisterMcServer(&user, &mcServer, "No prepared container available")
+	if err != nil {
+		manager.RemovePreparingServer(serverID)
+		manager.RemovePortFromUsageList(port)
+		sendError("Couldn't add mc server to database", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, mcServer.ToClientJson())
+
 	go func() {
+		defer manager.RemovePreparingServer(serverID)
 
+		if fromBackup != nil {
+			utils.ChanSendString(preparationChan, fmt.Sprintf("Using the world of backup %d", fromBackup.ID))
+		} else if !worldProfile.IsDefault() {
+			utils.ChanSendString(preparationChan, "No prepared world with the requested world generation settings available")
+		}
 		// We need to check if the docker image is prepared
 		utils.ChanSendString(preparationChan, "Preparing server preparation")
 		manager.EnsureImageIsReady(config.ImageWithMcVersion(mcVersion))
@@ -200,7 +466,6 @@ func startServer(w http.ResponseWriter, r *http.Request) {
 		// we need to prepare a server with given mc version
 		utils.ChanSendString(preparationChan, "Starting server preparation")
 
-		port := manager.GeneratePort()
 		authKey := manager.GenerateAuthKeyForMcServer()
 
 		coreBootUpWaitGroup := sync.WaitGroup{}
@@ -212,6 +477,7 @@ func startServer(w http.ResponseWriter, r *http.Request) {
 			RamSizeMB:    targetRamSize,
 			ServerID:     serverID,
 			AutoDeploy:   true,
+			WorldProfile: worldProfile,
 		})
 		coreBootUpWaitGroup.Wait()
 		worldGenerationChan, err := mcserverapi.GetWorldGenerationChan(port, authKey)
@@ -227,26 +493,37 @@ func startServer(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		if err := manager.TransitionServerState(&dbServer, enums.Starting, manager.SystemUserID, "World generated"); err != nil {
+			log.Error().Err(err).Msgf("Couldn't update state of server %s", serverID)
+		}
+
 		utils.ChanSendString(preparationChan, "Waiting for preparation end")
 		manager.WaitForTargetServerPrepared(mcVersion) // TODO should be migrated to dedicated sync.WaitGroup
 		mcServer, err := manager.GetMcServerContainerByServerID(serverID, name)
 		if err != nil {
 			utils.ChanSendString(preparationChan, "Couldn't end preparation")
+			manager.TransitionServerState(&dbServer, enums.Crashed, manager.SystemUserID, "Container not found after preparation")
+			return
 		}
 
-		if err := db.AddMcServerContainer(&user, &mcServer); err != nil {
+		if err := db.UpdateServerContainerID(&dbServer, mcServer.ContainerID); err != nil {
 			utils.ChanSendString(preparationChan, "Couldn't add server to database")
 			return
 		}
+		if err := manager.TransitionServerState(&dbServer, enums.Running, manager.SystemUserID, "Container is running"); err != nil {
+			log.Error().Err(err).Msgf("Couldn't update state of server %s", serverID)
+		}
 
 		utils.ChanSendString(preparationChan, "Done")
-		manager.RemovePreparingServer(serverID)
 	}()
 }
 
 func serverStartStatus(w http.ResponseWriter, r *http.Request) {
-	serverID := mux.Vars(r)["serverid"]
-	prepChan := manager.GetPreparingServerChan(serverID)
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	prepChan := manager.GetPreparingServerChan(mcServerData.ServerID)
 	if prepChan == nil {
 		// channel not found, probably serverID not found
 		sendError("Server not found", w, http.StatusNotFound)
@@ -260,7 +537,11 @@ func serverStartStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for {
-		message := <-prepChan
+		// the channel is closed when the preparation ends, even if it failed
+		message, ok := <-prepChan
+		if !ok {
+			break
+		}
 		conn.WriteJSON(map[string]string{
 			"message": message,
 		})
@@ -272,12 +553,9 @@ func serverStartStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 func serverStats(w http.ResponseWriter, r *http.Request) {
-	serverID := mux.Vars(r)["serverid"]
-
 	// check if server exist
-	serverData, err := db.GetMcServerData(serverID)
-	if err != nil {
-		sendError("Server with given ID not found", w, http.StatusNotFound)
+	serverData, _, ok := getAccessibleServer(w, r)
+	if !ok {
 		return
 	}
 
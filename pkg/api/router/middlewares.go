@@ -1,22 +1,45 @@
 package router
 
 import (
+	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
 	"net/http"
+	"time"
 )
 
 func authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
-		if r.URL.Path != "/api/" && r.URL.Path != "/api/login" {
+		if !registeredRoutes[mux.CurrentRoute(r)].public {
 			clientAuthKey := r.Header.Get("auth")
-			// searching for session...
-			_, err := db.GetSession(clientAuthKey)
+			if isAPIToken(clientAuthKey) {
+				if !authenticateAPIToken(clientAuthKey, w, r) {
+					return
+				}
+			} else {
+				// searching for session...
+				session, err := db.GetSession(clientAuthKey)
 
-			if err != nil {
-				// Authentication failed
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
+				if err != nil {
+					// Authentication failed
+					sendError("Unauthorized", w, http.StatusUnauthorized)
+					return
+				}
+				if session.IsExpired(time.Now()) {
+					db.RevokeSession(&session)
+					sendError("Session expired", w, http.StatusUnauthorized)
+					return
+				}
+				if err := db.TouchSession(&session); err != nil {
+					log.Warn().Err(err).Msgf("Couldn't update last usage of session %d", session.ID)
+				}
+				if !checkPasswordChange(&session.User, w, r) {
+					return
+				}
 			}
 		}
 
@@ -24,3 +47,62 @@ func authMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// authenticateAPIToken Checks if the API token exists and has the scope of the requested route
+// If the token is restricted to a server, only routes of this server can be accessed
+// Otherwise an error response is sent and false is returned
+func authenticateAPIToken(token string, w http.ResponseWriter, r *http.Request) bool {
+	apiToken, err := db.GetAPIToken(token)
+	if err != nil {
+		sendError("Unauthorized", w, http.StatusUnauthorized)
+		return false
+	}
+	if apiToken.IsExpired(time.Now()) {
+		sendError("API token expired", w, http.StatusUnauthorized)
+		return false
+	}
+
+	scope := registeredRoutes[mux.CurrentRoute(r)].scope
+	if scope == "" {
+		sendError("This route can't be accessed with an API token", w, http.StatusForbidden)
+		return false
+	}
+	if !apiToken.HasScope(scope) {
+		sendError(fmt.Sprintf("API token is missing the scope %s", scope), w, http.StatusForbidden)
+		return false
+	}
+	if apiToken.ServerID != "" && mux.Vars(r)["serverid"] != apiToken.ServerID {
+		sendError(fmt.Sprintf("API token is restricted to server %s", apiToken.ServerID), w, http.StatusForbidden)
+		return false
+	}
+
+	if err := db.TouchAPIToken(&apiToken); err != nil {
+		log.Warn().Err(err).Msgf("Couldn't update last usage of API token %d", apiToken.ID)
+	}
+	return checkPasswordChange(&apiToken.User, w, r)
+}
+
+// checkPasswordChange Sends an error and returns false if the user has to change the password before using the route
+func checkPasswordChange(user *models.User, w http.ResponseWriter, r *http.Request) bool {
+	if !user.MustChangePassword || registeredRoutes[mux.CurrentRoute(r)].allowedDuringPasswordChange {
+		return true
+	}
+	sendError("Password change required", w, http.StatusForbidden)
+	return false
+}
+
+// requireRole Only calls the handler if the current user has at least the given role
+func requireRole(role enums.UserRole, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := getCurrentUser(r)
+		if err != nil {
+			sendError("Could not fetch user", w, http.StatusUnauthorized)
+			return
+		}
+		if !user.Role.IsAtLeast(role) {
+			sendError("Insufficient permissions", w, http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,232 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// openAPIDocument is generated once from apiRoutes on the first request
+var openAPIDocument map[string]interface{}
+var openAPIDocumentOnce sync.Once
+
+var pathParameterRegex = regexp.MustCompile(`{(\w+)}`)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func openAPIRoute(w http.ResponseWriter, r *http.Request) {
+	openAPIDocumentOnce.Do(func() {
+		openAPIDocument = generateOpenAPIDocument(apiRoutes)
+	})
+	sendJSON(w, http.StatusOK, openAPIDocument)
+}
+
+// generateOpenAPIDocument Describes the v2 api in the OpenAPI 3 format
+// The schemas are derived from the request, query and response types of the routes
+func generateOpenAPIDocument(routes []apiRoute) map[string]interface{} {
+	schemas := openAPISchemas{}
+	errorSchema := schemas.schemaOf(reflect.TypeOf(v2ErrorResponse{}))
+
+	paths := map[string]map[string]interface{}{}
+	for _, route := range routes {
+		operation := map[string]interface{}{
+			"summary":     route.summary,
+			"description": routeDescription(route),
+			"responses": map[string]interface{}{
+				"default": jsonContent("Error", errorSchema),
+			},
+		}
+		responses := operation["responses"].(map[string]interface{})
+		if route.websocket {
+			responses["101"] = map[string]interface{}{"description": "Switching to a websocket connection"}
+		} else if route.fileResponse {
+			responses["200"] = binaryContent("File download", "application/octet-stream")
+		} else if route.response != nil {
+			responses["200"] = jsonContent("Success", schemas.schemaOf(reflect.TypeOf(route.response)))
+		}
+		if route.rawBody {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  binaryContent("", "application/octet-stream")["content"],
+			}
+		} else if route.request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schemaOf(reflect.TypeOf(route.request))},
+				},
+			}
+		}
+
+		var parameters []interface{}
+		for _, match := range pathParameterRegex.FindAllStringSubmatch(route.path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		if route.query != nil {
+			queryType := reflect.TypeOf(route.query)
+			for i := 0; i < queryType.NumField(); i++ {
+				name, required, ok := jsonFieldName(queryType.Field(i))
+				if !ok {
+					continue
+				}
+				parameters = append(parameters, map[string]interface{}{
+					"name": name, "in": "query", "required": required, "schema": schemas.schemaOf(queryType.Field(i).Type),
+				})
+			}
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+		if route.public {
+			operation["security"] = []interface{}{}
+		}
+
+		if paths[route.path] == nil {
+			paths[route.path] = map[string]interface{}{}
+		}
+		paths[route.path][strings.ToLower(route.method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "InstantMC",
+			"version": "2",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": apiV2Prefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"auth": map[string]interface{}{"type": "apiKey", "in": "header", "name": "auth"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"auth": []interface{}{}}},
+	}
+}
+
+// routeDescription Describes the permissions needed for the route
+func routeDescription(route apiRoute) string {
+	if route.public {
+		return "No authentication required"
+	}
+	description := fmt.Sprintf("Requires the role %s", route.role)
+	if route.scope != "" {
+		description += fmt.Sprintf(" or an API token with the scope %s", route.scope)
+	}
+	return description
+}
+
+func jsonContent(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+func binaryContent(description string, mediaType string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			mediaType: map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+		},
+	}
+}
+
+// openAPISchemas contains the schemas of named structs, they are referenced by their type name
+type openAPISchemas map[string]interface{}
+
+func (schemas openAPISchemas) schemaOf(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Pointer:
+		schema := schemas.schemaOf(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case t.Kind() == reflect.Struct:
+		if t.Name() == "" {
+			return schemas.structSchema(t)
+		}
+		if _, exists := schemas[t.Name()]; !exists {
+			// reserved before the fields are generated, in case the struct references itself
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = schemas.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemas.schemaOf(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemas.schemaOf(t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (schemas openAPISchemas) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			// encoding/json promotes the fields of embedded structs
+			embedded := schemas.structSchema(field.Type)
+			for name, property := range embedded["properties"].(map[string]interface{}) {
+				properties[name] = property
+			}
+			if embeddedRequired, ok := embedded["required"].([]string); ok {
+				required = append(required, embeddedRequired...)
+			}
+			continue
+		}
+		name, fieldRequired, ok := jsonFieldName(field)
+		if !ok {
+			continue
+		}
+		properties[name] = schemas.schemaOf(field.Type)
+		if fieldRequired {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if required != nil {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// jsonFieldName Returns the json name of the struct field and if it's always present
+// ok is false if the field isn't serialized
+func jsonFieldName(field reflect.StructField) (name string, required bool, ok bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, !strings.Contains(options, "omitempty"), true
+}
@@ -0,0 +1,58 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateOpenAPIDocument(t *testing.T) {
+	document := generateOpenAPIDocument(apiRoutes)
+	if _, err := json.Marshal(document); err != nil {
+		t.Fatalf("OpenAPI document can't be serialized: %s", err)
+	}
+
+	paths := document["paths"].(map[string]map[string]interface{})
+	for _, route := range apiRoutes {
+		if _, ok := paths[route.path][strings.ToLower(route.method)]; !ok {
+			t.Errorf("Route %s %s is missing in the OpenAPI document", route.method, route.path)
+		}
+	}
+
+	schemas := document["components"].(map[string]interface{})["schemas"].(openAPISchemas)
+	login, ok := schemas["loginRequest"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Schema of loginRequest is missing")
+	}
+	if required := login["required"].([]string); len(required) != 2 {
+		t.Errorf("loginRequest should require username and password, got %v", required)
+	}
+	if _, ok := schemas["ClientMcServer"]; !ok {
+		t.Errorf("Schema of ClientMcServer is missing")
+	}
+	worldProfile := schemas["WorldProfile"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := worldProfile["seed"]; !ok {
+		t.Errorf("The fields of embedded structs should be promoted, got %v", worldProfile)
+	}
+}
+
+func TestDecodeJSONBody(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v2/tokens?foo=bar", strings.NewReader(`{"name": "ci", "scopes": ["server:read", "server:start"], "expires_in_days": 30}`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := decodeJSONBody(httptest.NewRecorder(), r); err != nil {
+		t.Fatalf("Couldn't decode json body: %s", err)
+	}
+	if r.FormValue("name") != "ci" || r.FormValue("expires_in_days") != "30" || r.FormValue("foo") != "bar" {
+		t.Errorf("Unexpected form values %v", r.Form)
+	}
+	if scopes := r.Form["scopes"]; len(scopes) != 2 {
+		t.Errorf("Arrays should become multiple values, got %v", scopes)
+	}
+
+	r = httptest.NewRequest("POST", "/api/v2/login", strings.NewReader("username=admin"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := decodeJSONBody(httptest.NewRecorder(), r); err == nil {
+		t.Errorf("Form bodies should be rejected")
+	}
+}
@@ -0,0 +1,100 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/api/mojangapi"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strings"
+)
+
+// getPlayers Lists the online players and the whitelist, the ops and the banned players of the server
+// `online` is null if the server isn't running
+func getPlayers(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	var response playersResponse
+	if onlinePlayers, err := manager.GetOnlinePlayers(&mcServerData); err == nil {
+		response.Online = &onlinePlayers
+	} else if !errors.Is(err, manager.ErrServerNotRunning) {
+		log.Warn().Err(err).Msgf("Couldn't list online players of server %s", mcServerData.ServerID)
+	}
+	var err error
+	if response.Whitelist, err = manager.GetWhitelist(&mcServerData); err == nil {
+		if response.Ops, err = manager.GetOps(&mcServerData); err == nil {
+			response.Bans, err = manager.GetBannedPlayers(&mcServerData)
+		}
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Couldn't read player lists of server %s", mcServerData.ServerID)
+		sendError("Couldn't read player lists", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, response)
+}
+
+func addWhitelistedPlayer(w http.ResponseWriter, r *http.Request) {
+	changePlayerList(w, r, r.FormValue("name"), manager.AddToWhitelist)
+}
+
+func removeWhitelistedPlayer(w http.ResponseWriter, r *http.Request) {
+	changePlayerList(w, r, mux.Vars(r)["player"], manager.RemoveFromWhitelist)
+}
+
+func addOp(w http.ResponseWriter, r *http.Request) {
+	changePlayerList(w, r, r.FormValue("name"), manager.AddOp)
+}
+
+func removeOp(w http.ResponseWriter, r *http.Request) {
+	changePlayerList(w, r, mux.Vars(r)["player"], manager.RemoveOp)
+}
+
+func banPlayer(w http.ResponseWriter, r *http.Request) {
+	reason := strings.TrimSpace(r.FormValue("reason")) // Optional
+	if len(reason) > config.BanReasonMaximumLength || strings.ContainsAny(reason, "\r\n") {
+		sendError(fmt.Sprintf("The reason must be a single line with at most %d characters", config.BanReasonMaximumLength), w, http.StatusBadRequest)
+		return
+	}
+	changePlayerList(w, r, r.FormValue("name"), func(server *models.DBMcServerContainer, name string) error {
+		user, err := getCurrentUser(r)
+		if err != nil {
+			return err
+		}
+		return manager.BanPlayer(server, name, reason, user.Username)
+	})
+}
+
+func pardonPlayer(w http.ResponseWriter, r *http.Request) {
+	changePlayerList(w, r, mux.Vars(r)["player"], manager.PardonPlayer)
+}
+
+// changePlayerList Applies the change to a player list of the server and responds with the updated lists
+func changePlayerList(w http.ResponseWriter, r *http.Request, name string, change func(server *models.DBMcServerContainer, name string) error) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	setAuditDetails(r, name)
+	if err := change(&mcServerData, name); err != nil {
+		switch {
+		case errors.Is(err, manager.ErrInvalidPlayerName):
+			sendError("Player names consist of 1 to 16 letters, digits or underscores", w, http.StatusBadRequest)
+		case errors.Is(err, manager.ErrPlayerNotListed):
+			sendError("Player is not on the list", w, http.StatusNotFound)
+		case errors.Is(err, mojangapi.ErrPlayerNotFound):
+			sendError("Player doesn't exist", w, http.StatusNotFound)
+		default:
+			log.Error().Err(err).Msgf("Couldn't change player list of server %s", mcServerData.ServerID)
+			sendError("Couldn't change player list", w, http.StatusBadGateway)
+		}
+		return
+	}
+	getPlayers(w, r)
+}
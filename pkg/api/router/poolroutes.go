@@ -0,0 +1,126 @@
+package router
+
+import (
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"strconv"
+)
+
+func getPool(w http.ResponseWriter, r *http.Request) {
+	prepared, err := manager.GetPreparedPoolState()
+	if err != nil {
+		sendError("Couldn't fetch prepared server", w, http.StatusInternalServerError)
+		return
+	}
+
+	autoscaled, err := manager.GetAutoscaledPoolTargets()
+	if err != nil {
+		sendError("Couldn't calculate autoscaled pool targets", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, poolResponse{
+		Targets:    nonNilPoolTargets(manager.GetPoolTargets()),
+		Autoscaled: nonNilPoolTargets(autoscaled),
+		Prepared:   nonNilPoolTargets(prepared),
+	})
+}
+
+func setPoolTarget(w http.ResponseWriter, r *http.Request) {
+	mcVersion := r.FormValue("mc_version")
+	if mcVersion == "" {
+		sendError("Please provide the field \"mc_version\"", w, http.StatusBadRequest)
+		return
+	}
+	count, err := strconv.Atoi(r.FormValue("count"))
+	if err != nil {
+		sendError("Please provide the numeric field \"count\"", w, http.StatusBadRequest)
+		return
+	}
+	targetRamSizeRaw := r.FormValue("ram") // Optional
+	var targetRamSize int = config.DefaultRamSize
+	if targetRamSizeRaw != "" {
+		targetRamSize, err = strconv.Atoi(targetRamSizeRaw)
+		if err != nil {
+			sendError("Couldn't parse field \"ram\"", w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	profile := r.FormValue("profile") // Optional
+	if err := manager.SetPoolTarget(models.PoolTarget{McVersion: mcVersion, RamSizeMB: targetRamSize, Profile: profile, Count: count}); err != nil {
+		sendError(err.Error(), w, http.StatusBadRequest)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, poolTargetsResponse{Targets: nonNilPoolTargets(manager.GetPoolTargets())})
+}
+
+func getPoolStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := manager.GetPoolStats()
+	if err != nil {
+		sendError("Couldn't fetch pool statistics", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, stats)
+}
+
+func getWorldProfiles(w http.ResponseWriter, r *http.Request) {
+	profiles, err := manager.GetWorldProfiles()
+	if err != nil {
+		sendError("Couldn't fetch world profiles", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, worldProfilesResponse{Profiles: profiles})
+}
+
+// setWorldProfile Creates or updates a named world profile which can be used by pool targets and start requests
+func setWorldProfile(w http.ResponseWriter, r *http.Request) {
+	profile := models.WorldProfile{
+		Name: mux.Vars(r)["profile"],
+		WorldGenerationProfile: models.WorldGenerationProfile{
+			Seed:      r.FormValue("seed"),
+			LevelType: r.FormValue("level_type"),
+			GameMode:  r.FormValue("gamemode"),
+		},
+	}
+	if err := manager.SetWorldProfile(profile); err != nil {
+		var invalid manager.InvalidServerPropertiesError
+		if errors.As(err, &invalid) || errors.Is(err, manager.ErrInvalidWorldProfileName) || errors.Is(err, manager.ErrEmptyWorldProfile) {
+			sendError(err.Error(), w, http.StatusBadRequest)
+			return
+		}
+		log.Error().Err(err).Msgf("Couldn't save world profile %s", profile.Name)
+		sendError("Couldn't save world profile", w, http.StatusInternalServerError)
+		return
+	}
+	getWorldProfiles(w, r)
+}
+
+// deleteWorldProfile Removes the profile including its pool targets and prepared containers
+func deleteWorldProfile(w http.ResponseWriter, r *http.Request) {
+	if err := manager.DeleteWorldProfile(mux.Vars(r)["profile"]); err != nil {
+		if errors.Is(err, manager.ErrWorldProfileNotFound) {
+			sendError("World profile doesn't exist", w, http.StatusNotFound)
+			return
+		}
+		log.Error().Err(err).Msgf("Couldn't delete world profile %s", mux.Vars(r)["profile"])
+		sendError("Couldn't delete world profile", w, http.StatusInternalServerError)
+		return
+	}
+	getWorldProfiles(w, r)
+}
+
+// nonNilPoolTargets makes sure an empty list is sent as [] instead of null
+func nonNilPoolTargets(targets []models.PoolTarget) []models.PoolTarget {
+	if targets == nil {
+		return []models.PoolTarget{}
+	}
+	return targets
+}
@@ -0,0 +1,107 @@
+package router
+
+import (
+	"errors"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/rs/zerolog/log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// getServerProperties Returns the settings of server.properties which can be changed via the api
+func getServerProperties(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	properties, err := manager.GetServerProperties(&mcServerData)
+	if err != nil {
+		log.Error().Err(err).Msgf("Couldn't read server properties of server %s", mcServerData.ServerID)
+		sendError("Couldn't read server properties", w, http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, http.StatusOK, serverPropertiesResponse{Properties: properties})
+}
+
+// updateServerProperties Writes the properties of the request body to server.properties
+// The mc server only reads the file on startup, a running server is restarted if `restart` is true
+func updateServerProperties(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	restart := false
+	if restartRaw := r.FormValue("restart"); restartRaw != "" { // Optional
+		var err error
+		if restart, err = strconv.ParseBool(restartRaw); err != nil {
+			sendError("\"restart\" must be true or false", w, http.StatusBadRequest)
+			return
+		}
+	}
+	values := map[string]string{}
+	for key, formValues := range r.PostForm {
+		if key != "restart" && len(formValues) > 0 {
+			values[key] = formValues[0]
+		}
+	}
+	if len(values) == 0 {
+		sendError("Please provide at least one property", w, http.StatusBadRequest)
+		return
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	setAuditDetails(r, strings.Join(keys, ", "))
+
+	if err := manager.UpdateServerProperties(&mcServerData, values); err != nil {
+		sendServerPropertiesError(err, w)
+		return
+	}
+
+	response := serverPropertiesUpdateResponse{}
+	if mcServerData.Status == enums.Running {
+		if restart {
+			if err := manager.StopMcServer(&mcServerData, user.ID, "Restart after properties change"); err != nil {
+				sendStateTransitionError(err, w)
+				log.Error().Err(err).Msgf("Couldn't stop server %s", mcServerData.ServerID)
+				return
+			}
+			if err := manager.StartSavedMcServer(&mcServerData, user.ID, "Restart after properties change"); err != nil {
+				sendStateTransitionError(err, w)
+				log.Error().Err(err).Msgf("Couldn't start server %s", mcServerData.ServerID)
+				return
+			}
+			response.Restarted = true
+		} else {
+			response.RestartRequired = true
+		}
+	}
+	properties, err := manager.GetServerProperties(&mcServerData)
+	if err != nil {
+		log.Error().Err(err).Msgf("Couldn't read server properties of server %s", mcServerData.ServerID)
+		sendError("Couldn't read server properties", w, http.StatusInternalServerError)
+		return
+	}
+	response.Properties = properties
+	sendJSON(w, http.StatusOK, response)
+}
+
+// sendServerPropertiesError Responds with 400 and the reason per property if the properties aren't valid
+func sendServerPropertiesError(err error, w http.ResponseWriter) {
+	var invalid manager.InvalidServerPropertiesError
+	if !errors.As(err, &invalid) {
+		log.Error().Err(err).Msg("Couldn't write server properties")
+		sendError("Couldn't write server properties", w, http.StatusInternalServerError)
+		return
+	}
+	details := map[string]interface{}{}
+	for key, reason := range invalid {
+		details[key] = reason
+	}
+	sendDetailedError("Invalid server properties: "+invalid.Error(), details, w, http.StatusBadRequest)
+}
@@ -3,27 +3,140 @@ package router
 import (
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
 	"github.com/rs/zerolog/log"
 	"net/http"
 )
 
 const Port = 25000
 
+// apiRoute describes a route of the api. Every route is registered for v1 and v2, the OpenAPI document is generated from them
+type apiRoute struct {
+	method  string
+	path    string
+	summary string
+	handler http.HandlerFunc
+	// role is the minimum role of the current user, it's ignored for public routes
+	role   enums.UserRole
+	public bool
+	// allowedDuringPasswordChange routes can be used by users who need to change their password
+	allowedDuringPasswordChange bool
+	// scope is required by API tokens. Routes without scope can't be accessed with API tokens
+	scope enums.APIScope
+	// request is the type of the json body (v2) or form values (v1), query the type of the query parameters
+	request interface{}
+	query   interface{}
+	// response is the type of a successful response. It's nil for websockets and file downloads
+	response  interface{}
+	websocket bool
+	// rawBody routes read a binary request body themselves instead of json or form values, e.g. file uploads
+	rawBody bool
+	// fileResponse routes respond with a file download instead of json
+	fileResponse bool
+}
+
+var apiRoutes = []apiRoute{
+	{method: "GET", path: "/", summary: "Identifies the InstantMC server", handler: rootRoute, public: true, response: rootResponse{}},
+
+	{method: "POST", path: "/login", summary: "Creates a session", handler: loginRoute, public: true, request: loginRequest{}, response: loginResponse{}},
+	{method: "POST", path: "/logout", summary: "Revokes the current session", handler: logoutRoute, role: enums.Viewer, allowedDuringPasswordChange: true, response: emptyResponse{}},
+	{method: "POST", path: "/user/password/change", summary: "Changes the password of the current user and creates a new session", handler: passwordChange, role: enums.Viewer, allowedDuringPasswordChange: true, request: passwordChangeRequest{}, response: sessionTokenResponse{}},
+	{method: "GET", path: "/sessions", summary: "Lists the sessions of the current user", handler: getSessions, role: enums.Viewer, response: sessionsResponse{}},
+	{method: "DELETE", path: "/sessions/{sessionid}", summary: "Revokes a session of the current user", handler: deleteSession, role: enums.Viewer, response: emptyResponse{}},
+	{method: "GET", path: "/tokens", summary: "Lists the API tokens of the current user", handler: getAPITokens, role: enums.Viewer, response: apiTokensResponse{}},
+	{method: "POST", path: "/tokens", summary: "Creates an API token", handler: createAPIToken, role: enums.Viewer, request: createAPITokenRequest{}, response: createdAPITokenResponse{}},
+	{method: "DELETE", path: "/tokens/{tokenid}", summary: "Deletes an API token of the current user", handler: deleteAPIToken, role: enums.Viewer, response: emptyResponse{}},
+
+	{method: "GET", path: "/users", summary: "Lists all users", handler: getUsers, role: enums.Admin, response: usersResponse{}},
+	{method: "POST", path: "/users", summary: "Creates a user", handler: createUser, role: enums.Admin, request: createUserRequest{}, response: models.ClientUser{}},
+	{method: "PUT", path: "/users/{userid}", summary: "Changes the password or the role of a user", handler: updateUser, role: enums.Admin, request: updateUserRequest{}, response: models.ClientUser{}},
+	{method: "DELETE", path: "/users/{userid}", summary: "Deletes a user", handler: deleteUser, role: enums.Admin, response: emptyResponse{}},
+	{method: "GET", path: "/lockouts", summary: "Lists the failed logins per username and ip address", handler: getLoginFailures, role: enums.Admin, response: lockoutsResponse{}},
+	{method: "DELETE", path: "/lockouts/{lockoutid}", summary: "Forgets failed logins and lifts the lockout", handler: deleteLoginFailure, role: enums.Admin, response: emptyResponse{}},
+
+	{method: "GET", path: "/server", summary: "Lists the mc server of the current user, admins see all", handler: getServer, role: enums.Viewer, scope: enums.ScopeServerRead, response: serverListResponse{}},
+	{method: "GET", path: "/server/prepared", summary: "Lists the prepared containers", handler: getPreparedServer, role: enums.Viewer, scope: enums.ScopeServerRead, response: preparedServerResponse{}},
+	{method: "GET", path: "/server/trash", summary: "Lists the deleted mc servers which can be undeleted", handler: getTrash, role: enums.Viewer, scope: enums.ScopeServerRead, response: trashResponse{}},
+	{method: "GET", path: "/server/{serverid}", summary: "Shows the details of a mc server including its container and resource limits", handler: getServerDetails, role: enums.Viewer, scope: enums.ScopeServerRead, response: models.McServerDetails{}},
+	{method: "POST", path: "/server/start", summary: "Starts a new mc server", handler: startServer, role: enums.Operator, scope: enums.ScopeServerStart, request: startServerRequest{}, response: models.ClientMcServer{}},
+	{method: "GET", path: "/server/start/status/{serverid}", summary: "Streams the preparation progress of a new mc server", handler: serverStartStatus, role: enums.Viewer, scope: enums.ScopeServerRead, websocket: true},
+	{method: "GET", path: "/server/stats/{serverid}", summary: "Streams the resource usage of a mc server", handler: serverStats, role: enums.Viewer, scope: enums.ScopeStatsRead, websocket: true},
+	{method: "POST", path: "/server/{serverid}/stop", summary: "Stops a mc server and keeps its world", handler: stopServer, role: enums.Operator, scope: enums.ScopeServerStop, response: models.ClientMcServer{}},
+	{method: "POST", path: "/server/{serverid}/start", summary: "Starts a stopped or crashed mc server", handler: startStoppedServer, role: enums.Operator, scope: enums.ScopeServerStart, response: models.ClientMcServer{}},
+	{method: "POST", path: "/server/{serverid}/restart", summary: "Restarts a mc server", handler: restartServer, role: enums.Operator, scope: enums.ScopeServerStart, response: models.ClientMcServer{}},
+	{method: "GET", path: "/server/{serverid}/history", summary: "Lists the state transitions of a mc server", handler: serverHistory, role: enums.Viewer, scope: enums.ScopeServerRead, response: serverHistoryResponse{}},
+	{method: "GET", path: "/server/{serverid}/console", summary: "Streams the log of a mc server and runs console commands", handler: serverConsole, role: enums.Viewer, scope: enums.ScopeServerConsole, query: consoleQuery{}, websocket: true},
+	{method: "POST", path: "/server/{serverid}/command", summary: "Runs a console command on a mc server and returns its output", handler: serverCommand, role: enums.Viewer, scope: enums.ScopeServerConsole, request: commandRequest{}, response: commandResponse{}},
+	{method: "GET", path: "/server/{serverid}/players", summary: "Lists the online players, the whitelist, the ops and the banned players of a mc server", handler: getPlayers, role: enums.Viewer, scope: enums.ScopeServerRead, response: playersResponse{}},
+	{method: "POST", path: "/server/{serverid}/players/whitelist", summary: "Adds a player to the whitelist of a mc server", handler: addWhitelistedPlayer, role: enums.Operator, scope: enums.ScopeServerConsole, request: addPlayerRequest{}, response: playersResponse{}},
+	{method: "DELETE", path: "/server/{serverid}/players/whitelist/{player}", summary: "Removes a player from the whitelist of a mc server", handler: removeWhitelistedPlayer, role: enums.Operator, scope: enums.ScopeServerConsole, response: playersResponse{}},
+	{method: "POST", path: "/server/{serverid}/players/ops", summary: "Makes a player an op of a mc server", handler: addOp, role: enums.Admin, scope: enums.ScopeServerConsole, request: addPlayerRequest{}, response: playersResponse{}},
+	{method: "DELETE", path: "/server/{serverid}/players/ops/{player}", summary: "Removes the op status of a player of a mc server", handler: removeOp, role: enums.Admin, scope: enums.ScopeServerConsole, response: playersResponse{}},
+	{method: "POST", path: "/server/{serverid}/players/bans", summary: "Bans a player from a mc server", handler: banPlayer, role: enums.Operator, scope: enums.ScopeServerConsole, request: banPlayerRequest{}, response: playersResponse{}},
+	{method: "DELETE", path: "/server/{serverid}/players/bans/{player}", summary: "Pardons a banned player of a mc server", handler: pardonPlayer, role: enums.Operator, scope: enums.ScopeServerConsole, response: playersResponse{}},
+	{method: "GET", path: "/server/{serverid}/properties", summary: "Returns the server.properties settings of a mc server", handler: getServerProperties, role: enums.Viewer, scope: enums.ScopeServerRead, response: serverPropertiesResponse{}},
+	{method: "PATCH", path: "/server/{serverid}/properties", summary: "Changes server.properties settings of a mc server and optionally restarts it", handler: updateServerProperties, role: enums.Operator, scope: enums.ScopeServerConfig, request: serverPropertiesRequest{}, response: serverPropertiesUpdateResponse{}},
+	{method: "GET", path: "/server/{serverid}/backups", summary: "Lists the world backups and the backup schedule of a mc server", handler: getBackups, role: enums.Viewer, scope: enums.ScopeServerRead, response: backupsResponse{}},
+	{method: "POST", path: "/server/{serverid}/backups", summary: "Creates a backup of the world of a mc server", handler: createBackup, role: enums.Operator, scope: enums.ScopeServerBackup, response: models.ClientBackup{}},
+	{method: "PUT", path: "/server/{serverid}/backups/schedule", summary: "Sets the backup schedule and retention policy of a mc server", handler: setBackupSchedule, role: enums.Operator, scope: enums.ScopeServerBackup, request: backupScheduleRequest{}, response: models.ClientBackupSchedule{}},
+	{method: "DELETE", path: "/server/{serverid}/backups/schedule", summary: "Removes the backup schedule of a mc server", handler: deleteBackupSchedule, role: enums.Operator, scope: enums.ScopeServerBackup, response: emptyResponse{}},
+	{method: "DELETE", path: "/server/{serverid}/backups/{backupid}", summary: "Deletes a world backup of a mc server", handler: deleteBackup, role: enums.Operator, scope: enums.ScopeServerBackup, response: emptyResponse{}},
+	{method: "POST", path: "/server/{serverid}/restore", summary: "Replaces the world of a mc server with a backup and restarts it", handler: restoreServer, role: enums.Operator, scope: enums.ScopeServerBackup, request: restoreRequest{}, response: models.ClientMcServer{}},
+	{method: "GET", path: "/server/{serverid}/world/download", summary: "Downloads the world of a mc server as tar.gz or zip archive", handler: downloadWorld, role: enums.Viewer, scope: enums.ScopeServerRead, query: worldDownloadQuery{}, fileResponse: true},
+	{method: "POST", path: "/server/{serverid}/world/upload", summary: "Replaces the world of a mc server with an uploaded tar.gz or zip archive", handler: uploadWorld, role: enums.Operator, scope: enums.ScopeServerBackup, rawBody: true, response: models.ClientMcServer{}},
+	{method: "GET", path: "/server/{serverid}/world/upload", summary: "Shows the progress of the last world upload of a mc server", handler: getWorldUploadProgress, role: enums.Viewer, scope: enums.ScopeServerRead, response: models.WorldUploadProgress{}},
+	{method: "GET", path: "/server/{serverid}/files", summary: "Lists a directory of a mc server", handler: listServerFiles, role: enums.Operator, scope: enums.ScopeServerConfig, query: serverFileQuery{}, response: serverFilesResponse{}},
+	{method: "DELETE", path: "/server/{serverid}/files", summary: "Deletes a file or directory of a mc server", handler: deleteServerFile, role: enums.Admin, scope: enums.ScopeServerConfig, query: serverFileQuery{}, response: emptyResponse{}},
+	{method: "GET", path: "/server/{serverid}/files/content", summary: "Downloads a file of a mc server", handler: readServerFile, role: enums.Operator, scope: enums.ScopeServerConfig, query: serverFileQuery{}, fileResponse: true},
+	{method: "PUT", path: "/server/{serverid}/files/content", summary: "Creates or replaces a file of a mc server with the request body", handler: writeServerFile, role: enums.Admin, scope: enums.ScopeServerConfig, query: serverFileQuery{}, rawBody: true, response: emptyResponse{}},
+	{method: "POST", path: "/server/{serverid}/files/mkdir", summary: "Creates a directory of a mc server", handler: makeServerDir, role: enums.Admin, scope: enums.ScopeServerConfig, request: serverFileRequest{}, response: emptyResponse{}},
+	{method: "POST", path: "/server/{serverid}/files/move", summary: "Moves or renames a file or directory of a mc server", handler: moveServerFile, role: enums.Admin, scope: enums.ScopeServerConfig, request: moveServerFileRequest{}, response: emptyResponse{}},
+	{method: "GET", path: "/server/{serverid}/logs", summary: "Searches the current and the archived log of a mc server", handler: serverLogs, role: enums.Viewer, scope: enums.ScopeServerConsole, query: logsQuery{}, response: logsResponse{}},
+	{method: "DELETE", path: "/server/{serverid}/delete", summary: "Moves a mc server including its world to the trash", handler: deleteServer, role: enums.Operator, scope: enums.ScopeServerDelete, response: emptyResponse{}},
+	{method: "POST", path: "/server/{serverid}/undelete", summary: "Restores a deleted mc server from the trash", handler: undeleteServer, role: enums.Operator, scope: enums.ScopeServerDelete, response: models.ClientMcServer{}},
+
+	{method: "GET", path: "/pool", summary: "Shows the configured, autoscaled and prepared pool containers", handler: getPool, role: enums.Viewer, response: poolResponse{}},
+	{method: "PUT", path: "/pool", summary: "Sets the pool target of a mc version and ram size", handler: setPoolTarget, role: enums.Admin, request: setPoolTargetRequest{}, response: poolTargetsResponse{}},
+	{method: "GET", path: "/pool/profiles", summary: "Lists the named world generation profiles", handler: getWorldProfiles, role: enums.Viewer, response: worldProfilesResponse{}},
+	{method: "PUT", path: "/pool/profiles/{profile}", summary: "Creates or updates a named world generation profile", handler: setWorldProfile, role: enums.Admin, request: models.WorldGenerationProfile{}, response: worldProfilesResponse{}},
+	{method: "DELETE", path: "/pool/profiles/{profile}", summary: "Deletes a world generation profile, its pool targets and prepared containers", handler: deleteWorldProfile, role: enums.Admin, response: worldProfilesResponse{}},
+	{method: "GET", path: "/pool/stats", summary: "Shows how many start requests were served by prepared containers", handler: getPoolStats, role: enums.Viewer, response: models.PoolStats{}},
+
+	{method: "GET", path: "/system/resources", summary: "Shows the host resources", handler: getSystemResources, role: enums.Admin, response: models.HostResources{}},
+	{method: "GET", path: "/audit", summary: "Lists the audit log", handler: getAuditLog, role: enums.Admin, query: auditLogQuery{}, response: auditLogResponse{}},
+}
+
+// registeredRoutes maps the mux routes of v1 and v2 to their description, it's used by the authMiddleware
+var registeredRoutes = map[*mux.Route]apiRoute{}
+
+// registerAPIRoutes Registers all apiRoutes at the router
+func registerAPIRoutes(router *mux.Router) {
+	for _, route := range apiRoutes {
+		handler := route.handler
+		if !route.public {
+			handler = requireRole(route.role, handler)
+		}
+		registeredRoutes[router.HandleFunc(route.path, handler).Methods(route.method)] = route
+	}
+}
+
 func Register() *mux.Router {
 	r := mux.NewRouter()
-	api := r.PathPrefix("/api").Subrouter()
-	api.Use(authMiddleware)
-	api.HandleFunc("/", rootRoute).Methods("GET")
 
-	api.HandleFunc("/login", loginRoute).Methods("POST")
-	api.HandleFunc("/user/password/change", passwordChange).Methods("POST")
+	// v2 has to be registered before v1, otherwise the v1 prefix would match its requests
+	v2 := r.PathPrefix(apiV2Prefix).Subrouter()
+	// the audit middleware runs first, so requests with rejected bodies are audited as well
+	v2.Use(auditMiddleware)
+	v2.Use(v2Middleware)
+	v2.Use(authMiddleware)
+	registerAPIRoutes(v2)
+	registeredRoutes[v2.HandleFunc("/openapi.json", openAPIRoute).Methods("GET")] = apiRoute{public: true}
 
-	api.HandleFunc("/server", getServer).Methods("GET")
-	api.HandleFunc("/server/prepared", getPreparedServer).Methods("GET")
-	api.HandleFunc("/server/start", startServer).Methods("POST")
-	api.HandleFunc("/server/start/status/{serverid}", serverStartStatus).Methods("GET")
-	api.HandleFunc("/server/stats/{serverid}", serverStats).Methods("GET")
-	api.HandleFunc("/server/{serverid}/delete", deleteServer).Methods("DELETE")
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(auditMiddleware)
+	api.Use(authMiddleware)
+	registerAPIRoutes(api)
 
 	// Flutter frontend
 	fs := http.FileServer(http.Dir("./frontend/"))
@@ -12,17 +12,25 @@ var Upgrader = websocket.Upgrader{
 }
 
 func sendError(error string, w http.ResponseWriter, status int) {
-	data, _ := json.Marshal(map[string]interface{}{
-		"error": error,
-	})
+	sendDetailedError(error, nil, w, status)
+}
+
+// sendDetailedError Sends an error with additional machine readable details. The details are only part of v2 responses
+func sendDetailedError(message string, details map[string]interface{}, w http.ResponseWriter, status int) {
+	if isV2Response(w) {
+		sendJSON(w, status, v2ErrorResponse{Error: apiError{Code: errorCode(status), Message: message, Details: details}})
+		return
+	}
+	sendJSON(w, status, errorResponse{Error: message})
+}
+
+func sendJSON(w http.ResponseWriter, status int, response interface{}) {
+	data, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	w.Write(data)
 }
 
 func rootRoute(w http.ResponseWriter, r *http.Request) {
-	data, _ := json.Marshal(map[string]interface{}{
-		"server": "InstantMC",
-	})
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	sendJSON(w, http.StatusOK, rootResponse{Server: "InstantMC"})
 }
@@ -0,0 +1,69 @@
+package router
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/models"
+	"net/http"
+	"strconv"
+)
+
+func getSessions(w http.ResponseWriter, r *http.Request) {
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+	currentSession, err := getCurrentSession(r)
+	if err != nil {
+		sendError("Couldn't fetch current session", w, http.StatusInternalServerError)
+		return
+	}
+	sessions, err := db.GetSessionsOfUser(&user)
+	if err != nil {
+		sendError("Couldn't fetch sessions", w, http.StatusInternalServerError)
+		return
+	}
+
+	sessionData := []models.ClientSession{}
+	for _, session := range sessions {
+		sessionData = append(sessionData, session.ToClientJson(session.ID == currentSession.ID))
+	}
+
+	sendJSON(w, http.StatusOK, sessionsResponse{Sessions: sessionData})
+}
+
+func deleteSession(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := strconv.Atoi(mux.Vars(r)["sessionid"])
+	if err != nil {
+		sendError("Invalid session ID", w, http.StatusBadRequest)
+		return
+	}
+	user, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteSession(&user, uint(sessionID)); err != nil {
+		sendError("Session with given ID doesn't exist", w, http.StatusNotFound)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+func logoutRoute(w http.ResponseWriter, r *http.Request) {
+	session, err := getCurrentSession(r)
+	if err != nil {
+		sendError("Couldn't fetch current session", w, http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.RevokeSession(&session); err != nil {
+		sendError("Couldn't delete session", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
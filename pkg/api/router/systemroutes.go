@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/instantmc/server/pkg/manager"
+	"net/http"
+)
+
+func getSystemResources(w http.ResponseWriter, r *http.Request) {
+	resources, err := manager.GetHostResources()
+	if err != nil {
+		sendError("Couldn't read host resources", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, resources)
+}
@@ -0,0 +1,260 @@
+package router
+
+import (
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+)
+
+// Request and response types of the api routes. v1 reads the requests from form values, v2 from json bodies
+// The OpenAPI document is generated from these types, so they have to match what the handlers read and write
+
+type emptyResponse struct{}
+
+// errorResponse is the error format of v1
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// apiError is the structured error of v2. Code is derived from the http status, e.g. `not_found`
+type apiError struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+type v2ErrorResponse struct {
+	Error apiError `json:"error"`
+}
+
+type rootResponse struct {
+	Server string `json:"server"`
+}
+
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type loginResponse struct {
+	Token                  string `json:"token"`
+	PasswordChangeRequired bool   `json:"password_change_required"`
+}
+
+type passwordChangeRequest struct {
+	Password string `json:"password"`
+}
+
+type sessionTokenResponse struct {
+	Token string `json:"token"`
+}
+
+type sessionsResponse struct {
+	Sessions []models.ClientSession `json:"sessions"`
+}
+
+type createAPITokenRequest struct {
+	Name          string           `json:"name"`
+	Scopes        []enums.APIScope `json:"scopes"`
+	ServerID      string           `json:"server_id,omitempty"`
+	ExpiresInDays int              `json:"expires_in_days,omitempty"`
+}
+
+type apiTokensResponse struct {
+	Tokens []models.ClientAPIToken `json:"tokens"`
+}
+
+type createdAPITokenResponse struct {
+	Token   string                `json:"token"`
+	Details models.ClientAPIToken `json:"details"`
+}
+
+type createUserRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Role     string `json:"role,omitempty"`
+}
+
+type updateUserRequest struct {
+	Password string `json:"password,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+type usersResponse struct {
+	Users []models.ClientUser `json:"users"`
+}
+
+type lockoutsResponse struct {
+	Lockouts []models.ClientLoginFailure `json:"lockouts"`
+}
+
+type startServerRequest struct {
+	Name      string `json:"name"`
+	McVersion string `json:"mc_version"`
+	RamSizeMB int    `json:"ram,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Seed      string `json:"seed,omitempty"`
+	LevelType string `json:"level_type,omitempty"`
+	GameMode  string `json:"gamemode,omitempty"`
+	// FromBackup is the ID of a backup whose world is used instead of generating a new one
+	FromBackup uint `json:"from_backup,omitempty"`
+}
+
+type worldDownloadQuery struct {
+	Format string `json:"format,omitempty"`
+}
+
+type serverFileQuery struct {
+	Path string `json:"path,omitempty"`
+}
+
+type serverFilesResponse struct {
+	Path  string              `json:"path"`
+	Files []models.ServerFile `json:"files"`
+}
+
+type serverFileRequest struct {
+	Path string `json:"path"`
+}
+
+type moveServerFileRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type restoreRequest struct {
+	BackupID uint `json:"backup_id"`
+}
+
+type serverListResponse struct {
+	Server []models.ClientMcServer `json:"server"`
+}
+
+type trashResponse struct {
+	Server []models.ClientDeletedMcServer `json:"server"`
+}
+
+type preparedServerResponse struct {
+	PreparedServer []models.PreparedContainer `json:"prepared_server"`
+}
+
+// consoleMessage is sent over the console websocket
+// Type is `log` for a log line, `output` for the result of a command, `error` or `closed` if the log stream ended
+type consoleMessage struct {
+	Type    string `json:"type"`
+	Line    string `json:"line,omitempty"`
+	Command string `json:"command,omitempty"`
+	Output  string `json:"output,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type consoleQuery struct {
+	Lines int `json:"lines,omitempty"`
+}
+
+// consoleCommand is sent by the client over the console websocket
+type consoleCommand struct {
+	Command string `json:"command"`
+}
+
+type commandRequest struct {
+	Command string `json:"command"`
+}
+
+type commandResponse struct {
+	Command string `json:"command"`
+	Output  string `json:"output"`
+}
+
+type playersResponse struct {
+	Online    *models.OnlinePlayers   `json:"online"`
+	Whitelist []models.WhitelistEntry `json:"whitelist"`
+	Ops       []models.OpEntry        `json:"ops"`
+	Bans      []models.BanEntry       `json:"bans"`
+}
+
+type addPlayerRequest struct {
+	Name string `json:"name"`
+}
+
+type banPlayerRequest struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type serverPropertiesResponse struct {
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// serverPropertiesRequest contains the server.properties keys which should be changed, e.g. `{"pvp": false}`
+type serverPropertiesRequest struct {
+	Restart bool `json:"restart,omitempty"`
+}
+
+type serverPropertiesUpdateResponse struct {
+	Properties map[string]interface{} `json:"properties"`
+	// Restarted is true if the running server has been restarted to apply the changes
+	Restarted bool `json:"restarted"`
+	// RestartRequired is true if the server is running and the changes take effect on the next start
+	RestartRequired bool `json:"restart_required"`
+}
+
+type logsQuery struct {
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+	Grep  string `json:"grep,omitempty"`
+	Tail  int    `json:"tail,omitempty"`
+}
+
+type logsResponse struct {
+	Lines []models.LogLine `json:"lines"`
+}
+
+type serverHistoryResponse struct {
+	History []models.ClientServerStateTransition `json:"history"`
+}
+
+type setPoolTargetRequest struct {
+	McVersion string `json:"mc_version"`
+	RamSizeMB int    `json:"ram,omitempty"`
+	Profile   string `json:"profile,omitempty"`
+	Count     int    `json:"count"`
+}
+
+type worldProfilesResponse struct {
+	Profiles []models.WorldProfile `json:"profiles"`
+}
+
+type backupsResponse struct {
+	Backups   []models.ClientBackup        `json:"backups"`
+	Schedule  *models.ClientBackupSchedule `json:"schedule"`
+	Retention models.BackupRetention       `json:"retention"`
+}
+
+type backupScheduleRequest struct {
+	Cron       string `json:"cron"`
+	KeepLast   int    `json:"keep_last,omitempty"`
+	KeepDaily  int    `json:"keep_daily,omitempty"`
+	KeepWeekly int    `json:"keep_weekly,omitempty"`
+}
+
+type poolResponse struct {
+	Targets    []models.PoolTarget `json:"targets"`
+	Autoscaled []models.PoolTarget `json:"autoscaled"`
+	Prepared   []models.PoolTarget `json:"prepared"`
+}
+
+type poolTargetsResponse struct {
+	Targets []models.PoolTarget `json:"targets"`
+}
+
+type auditLogQuery struct {
+	User   string `json:"user,omitempty"`
+	Server string `json:"server,omitempty"`
+	Since  string `json:"since,omitempty"`
+	Until  string `json:"until,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+type auditLogResponse struct {
+	Entries []models.ClientAuditEntry `json:"entries"`
+}
@@ -1,11 +1,20 @@
 package router
 
 import (
-	"encoding/json"
-	"github.com/instantmc/server/pkg/config"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/instantmc/server/pkg/models"
 	"github.com/instantmc/server/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func loginRoute(w http.ResponseWriter, r *http.Request) {
@@ -17,31 +26,59 @@ func loginRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// check if user exists
-	hashedPassword := utils.SHA256([]byte(password))
+	clientIP := getClientIP(r)
+	lockout, err := manager.ReserveLoginAttempt(username, clientIP)
+	if err != nil {
+		sendError("Couldn't check failed logins", w, http.StatusInternalServerError)
+		return
+	}
+	if lockout > 0 {
+		sendLoginLockoutError(lockout, w)
+		return
+	}
 
-	user, err := db.Login(username, hashedPassword)
+	// check if user exists
+	user, err := db.Login(username, password)
+	failed := errors.Is(err, db.ErrInvalidCredentials) || errors.Is(err, gorm.ErrRecordNotFound)
+	if err := manager.FinishLoginAttempt(username, clientIP, failed); err != nil {
+		log.Error().Err(err).Msg("Couldn't record failed login")
+	}
 	if err != nil {
 		// User doesn't exist
 		sendError("Invalid credentials", w, http.StatusUnauthorized)
 		return
 	}
+	if err := manager.ResetFailedLogins(username); err != nil {
+		log.Warn().Err(err).Msgf("Couldn't reset failed logins of user %s", username)
+	}
 
 	// creating session and token
-	token, err := db.CreateSession(&user)
+	token, err := db.CreateSession(&user, clientIP, r.UserAgent())
 	if err != nil {
 		// Couldn't create session
 		sendError("Couldn't create session", w, http.StatusInternalServerError)
 		return
 	}
 
+	// passwords which don't satisfy the current policy have to be changed before the api can be used
+	if !user.MustChangePassword && utils.CheckPasswordPolicy(password, username) != nil {
+		if err := db.SetMustChangePassword(&user, true); err != nil {
+			sendError("Couldn't update user", w, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Session successfully created
-	data, _ := json.Marshal(map[string]interface{}{
-		"token":                    token,
-		"password_change_required": password == config.PasswordRequiresChange,
-	})
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	sendJSON(w, http.StatusOK, loginResponse{Token: token, PasswordChangeRequired: user.MustChangePassword})
+}
+
+// sendLoginLockoutError Tells the client when the next login may be attempted
+func sendLoginLockoutError(lockout time.Duration, w http.ResponseWriter) {
+	retryAfter := int(math.Ceil(lockout.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	sendDetailedError(fmt.Sprintf("Too many failed logins, try again in %d seconds", retryAfter), map[string]interface{}{
+		"retry_after_seconds": retryAfter,
+	}, w, http.StatusTooManyRequests)
 }
 
 func passwordChange(w http.ResponseWriter, r *http.Request) {
@@ -59,22 +96,162 @@ func passwordChange(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := utils.CheckPasswordPolicy(password, user.Username); err != nil {
+		sendError(fmt.Sprintf("Password not allowed: %s", err.Error()), w, http.StatusBadRequest)
+		return
+	}
+
 	if err := db.UpdatePassword(&user, password); err != nil {
 		sendError("Couldn't update user password", w, http.StatusInternalServerError)
 		return
 	}
 
 	// db.UpdatePassword deletes all sessions for the user, we need to create a new one
-	sessionToken, err := db.CreateSession(&user)
+	sessionToken, err := db.CreateSession(&user, getClientIP(r), r.UserAgent())
 	if err != nil {
 		sendError("Couldn't create a new token", w, http.StatusInternalServerError)
 		return
 	}
 
 	// Session successfully created
-	data, _ := json.Marshal(map[string]interface{}{
-		"token": sessionToken,
-	})
-	w.WriteHeader(http.StatusOK)
-	w.Write(data)
+	sendJSON(w, http.StatusOK, sessionTokenResponse{Token: sessionToken})
+}
+
+func getUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := db.GetUsers()
+	if err != nil {
+		sendError("Couldn't fetch users", w, http.StatusInternalServerError)
+		return
+	}
+
+	userData := []models.ClientUser{}
+	for _, user := range users {
+		userData = append(userData, user.ToClientJson())
+	}
+
+	sendJSON(w, http.StatusOK, usersResponse{Users: userData})
+}
+
+func createUser(w http.ResponseWriter, r *http.Request) {
+	username := r.FormValue("username")
+	password := r.FormValue("password")
+	if username == "" || password == "" {
+		sendError("Please provide \"username\" and \"password\"", w, http.StatusBadRequest)
+		return
+	}
+	role := enums.Viewer
+	if roleRaw := r.FormValue("role"); roleRaw != "" { // Optional
+		var err error
+		role, err = enums.ParseUserRole(roleRaw)
+		if err != nil {
+			sendError(fmt.Sprintf("Unknown role %s", roleRaw), w, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := utils.CheckPasswordPolicy(password, username); err != nil {
+		sendError(fmt.Sprintf("Password not allowed: %s", err.Error()), w, http.StatusBadRequest)
+		return
+	}
+	if db.UsernameExists(username) {
+		sendError("Username already exists", w, http.StatusConflict)
+		return
+	}
+
+	user, err := db.CreateUser(username, password, role)
+	if err != nil {
+		sendError("Couldn't create user", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, user.ToClientJson())
+}
+
+func updateUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := getTargetUser(w, r)
+	if !ok {
+		return
+	}
+	password := r.FormValue("password") // Optional
+	if password != "" {
+		if err := utils.CheckPasswordPolicy(password, user.Username); err != nil {
+			sendError(fmt.Sprintf("Password not allowed: %s", err.Error()), w, http.StatusBadRequest)
+			return
+		}
+	}
+
+	if roleRaw := r.FormValue("role"); roleRaw != "" {
+		role, err := enums.ParseUserRole(roleRaw)
+		if err != nil {
+			sendError(fmt.Sprintf("Unknown role %s", roleRaw), w, http.StatusBadRequest)
+			return
+		}
+		if user.Role == enums.Admin && role != enums.Admin && isLastAdmin() {
+			sendError("The last admin can't lose the admin role", w, http.StatusConflict)
+			return
+		}
+		if err := db.UpdateUserRole(&user, role); err != nil {
+			sendError("Couldn't update user role", w, http.StatusInternalServerError)
+			return
+		}
+	}
+	if password != "" {
+		// all sessions of the user are deleted
+		if err := db.UpdatePassword(&user, password); err != nil {
+			sendError("Couldn't update user password", w, http.StatusInternalServerError)
+			return
+		}
+		// the admin knows the new password, so the user has to choose an own one
+		if currentUser, err := getCurrentUser(r); err == nil && currentUser.ID != user.ID {
+			if err := db.SetMustChangePassword(&user, true); err != nil {
+				sendError("Couldn't update user", w, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	sendJSON(w, http.StatusOK, user.ToClientJson())
+}
+
+func deleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := getTargetUser(w, r)
+	if !ok {
+		return
+	}
+	currentUser, err := getCurrentUser(r)
+	if err != nil {
+		sendError("Couldn't fetch current user", w, http.StatusInternalServerError)
+		return
+	}
+	if currentUser.ID == user.ID {
+		sendError("You can't delete yourself", w, http.StatusConflict)
+		return
+	}
+
+	// the servers of the user would be left without owner, the admin takes them over
+	if err := db.DeleteUser(&user, &currentUser); err != nil {
+		sendError("Couldn't delete user", w, http.StatusInternalServerError)
+		return
+	}
+
+	sendJSON(w, http.StatusOK, emptyResponse{})
+}
+
+// getTargetUser Returns the user of the `userid` route variable. Otherwise an error response is sent and false is returned
+func getTargetUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	userID, err := strconv.Atoi(mux.Vars(r)["userid"])
+	if err != nil {
+		sendError("Invalid user ID", w, http.StatusBadRequest)
+		return models.User{}, false
+	}
+	user, err := db.GetUser(uint(userID))
+	if err != nil {
+		sendError("User with given ID doesn't exist", w, http.StatusNotFound)
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func isLastAdmin() bool {
+	adminCount, err := db.CountUsersWithRole(enums.Admin)
+	return err != nil || adminCount <= 1
 }
@@ -0,0 +1,157 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const apiV2Prefix = "/api/v2"
+
+// maximumJSONBodySize limits the json bodies of v2 requests
+const maximumJSONBodySize = 1 << 20
+
+// v2ResponseWriter marks responses of v2 routes, sendError writes structured errors to it
+type v2ResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *v2ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack is needed for websocket connections
+func (w *v2ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer can't be hijacked")
+	}
+	return hijacker.Hijack()
+}
+
+// v2Middleware Decodes json bodies into the form values read by the handlers and marks the response as v2
+func v2Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v2Writer := &v2ResponseWriter{ResponseWriter: w}
+		if registeredRoutes[mux.CurrentRoute(r)].rawBody {
+			next.ServeHTTP(v2Writer, r)
+			return
+		}
+		if err := decodeJSONBody(w, r); err != nil {
+			var unsupported errUnsupportedMediaType
+			if errors.As(err, &unsupported) {
+				sendError(err.Error(), v2Writer, http.StatusUnsupportedMediaType)
+			} else {
+				sendError(fmt.Sprintf("Invalid json body: %s", err.Error()), v2Writer, http.StatusBadRequest)
+			}
+			return
+		}
+		next.ServeHTTP(v2Writer, r)
+	})
+}
+
+type errUnsupportedMediaType struct {
+	contentType string
+}
+
+func (err errUnsupportedMediaType) Error() string {
+	return fmt.Sprintf("Content-Type %s is not supported, use application/json", err.contentType)
+}
+
+// decodeJSONBody Adds the fields of a json object body to r.Form and r.PostForm
+// Arrays become multiple values of the field, nested objects aren't supported
+func decodeJSONBody(w http.ResponseWriter, r *http.Request) error {
+	if r.Body == nil || r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return nil
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maximumJSONBodySize))
+	if err != nil {
+		return err
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil
+	}
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			return errUnsupportedMediaType{contentType}
+		}
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(body)))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return err
+	}
+	// the body has been read, only the query is parsed
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		values, err := jsonFormValues(value)
+		if err != nil {
+			return fmt.Errorf("field \"%s\": %w", key, err)
+		}
+		for _, formValue := range values {
+			r.Form.Add(key, formValue)
+			r.PostForm.Add(key, formValue)
+		}
+	}
+	return nil
+}
+
+func jsonFormValues(value interface{}) ([]string, error) {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{typedValue}, nil
+	case json.Number:
+		return []string{typedValue.String()}, nil
+	case bool:
+		return []string{strconv.FormatBool(typedValue)}, nil
+	case []interface{}:
+		var result []string
+		for _, element := range typedValue {
+			if _, isArray := element.([]interface{}); isArray {
+				return nil, errors.New("nested arrays are not supported")
+			}
+			values, err := jsonFormValues(element)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, values...)
+		}
+		return result, nil
+	default:
+		return nil, errors.New("objects are not supported")
+	}
+}
+
+// isV2Response Returns true if the response writer belongs to a v2 route, middlewares may have wrapped it
+func isV2Response(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(*v2ResponseWriter); ok {
+			return true
+		}
+		wrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = wrapper.Unwrap()
+	}
+}
+
+// errorCode Returns the code of structured errors, e.g. `too_many_requests` for 429
+func errorCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
@@ -0,0 +1,94 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/manager"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// downloadWorld Sends the level of the server as tar.gz or zip archive, the archive is created before it's sent
+func downloadWorld(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	format := r.FormValue("format") // Optional
+	contentType := "application/gzip"
+	switch format {
+	case "", manager.WorldFormatTarGz:
+		format = manager.WorldFormatTarGz
+	case manager.WorldFormatZip:
+		contentType = "application/zip"
+	default:
+		sendError(fmt.Sprintf("Format %s isn't supported, use %s or %s", format, manager.WorldFormatTarGz, manager.WorldFormatZip), w, http.StatusBadRequest)
+		return
+	}
+
+	archive, err := manager.CreateWorldDownload(&mcServerData, format)
+	if errors.Is(err, manager.ErrWorldNotFound) {
+		sendError("The server has no world yet", w, http.StatusNotFound)
+		return
+	} else if errors.Is(err, manager.ErrWorldBusy) {
+		sendError("A backup or restore of the server is in progress", w, http.StatusConflict)
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Couldn't create world download of server %s", mcServerData.ServerID)
+		sendError("Couldn't create world archive", w, http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-world.%s\"", mcServerData.ServerID, format))
+	if info, err := archive.Stat(); err == nil {
+		w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, archive); err != nil {
+		// the status has already been sent, the client receives a truncated archive
+		log.Warn().Err(err).Msgf("World download of server %s failed", mcServerData.ServerID)
+	}
+}
+
+// uploadWorld Replaces the level of the server with the world of the tar.gz or zip archive in the request body
+// The request blocks until the world is replaced, its progress can be polled with getWorldUploadProgress
+func uploadWorld(w http.ResponseWriter, r *http.Request) {
+	mcServerData, user, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	if r.Body == nil {
+		sendError("Please send the world archive as request body", w, http.StatusBadRequest)
+		return
+	}
+
+	err := manager.ImportWorld(&mcServerData, r.Body, r.ContentLength, user.ID)
+	if errors.Is(err, manager.ErrWorldUploadTooLarge) {
+		sendError(err.Error(), w, http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		log.Error().Err(err).Msgf("Couldn't import world into server %s", mcServerData.ServerID)
+		sendRestoreError(err, w)
+		return
+	}
+	sendJSON(w, http.StatusOK, mcServerData.ToClientJson())
+}
+
+func getWorldUploadProgress(w http.ResponseWriter, r *http.Request) {
+	mcServerData, _, ok := getAccessibleServer(w, r)
+	if !ok {
+		return
+	}
+	progress, ok := manager.GetWorldUploadProgress(mcServerData.ServerID)
+	if !ok {
+		sendError("No world has been uploaded to the server", w, http.StatusNotFound)
+		return
+	}
+	sendJSON(w, http.StatusOK, progress)
+}
@@ -0,0 +1,6 @@
+package config
+
+// APITokenPrefix distinguishes API tokens from session tokens in the auth header
+const APITokenPrefix = "imc_"
+
+const APITokenLength = 40
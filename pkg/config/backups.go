@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// McBackupsDir contains the world backups in a directory per server
+const McBackupsDir = "backups"
+
+const (
+	// BackupSchedulerInterval defines how often the backup schedules are checked, cron expressions have a precision of a minute
+	BackupSchedulerInterval = time.Minute
+
+	// The retention policy of servers without schedule. Backups which aren't kept by any rule are deleted after each backup
+	// BackupDefaultKeepLast keeps the newest backups
+	BackupDefaultKeepLast = 5
+	// BackupDefaultKeepDaily keeps the newest backup of each of the last days with backups
+	BackupDefaultKeepDaily = 7
+	// BackupDefaultKeepWeekly keeps the newest backup of each of the last weeks with backups
+	BackupDefaultKeepWeekly = 4
+	// BackupMaximumKeep limits each retention rule
+	BackupMaximumKeep = 100
+)
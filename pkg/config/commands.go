@@ -0,0 +1,21 @@
+package config
+
+import "github.com/instantmc/server/pkg/enums"
+
+// Mc commands are matched by their leading words without slash and namespace, case-insensitive
+// e.g. the entry `time query` matches `/minecraft:time query daytime` but not `/time set day`
+// An entry ending with `$` only matches the command without further words, e.g. `difficulty$` queries the difficulty
+// but doesn't match `difficulty hard`. The commands run by `execute ... run` are checked against the denylist as well
+
+// CommandAllowlist limits the commands of a role. Roles without allowlist may run every command which isn't denied
+var CommandAllowlist = map[enums.UserRole][]string{
+	enums.Viewer: {"list", "help", "seed", "time query", "difficulty$"},
+}
+
+// CommandDenylist contains the commands a role must not run
+// `stop` would end the container behind the back of InstantMC, servers are stopped via the api instead
+var CommandDenylist = map[enums.UserRole][]string{
+	enums.Admin:    {"stop"},
+	enums.Operator: {"stop", "op", "deop", "save-off", "ban-ip", "pardon-ip"},
+	enums.Viewer:   {"stop", "op", "deop"},
+}
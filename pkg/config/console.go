@@ -0,0 +1,25 @@
+package config
+
+import "time"
+
+const (
+	// McCommandTimeout limits how long a console command may take until its output is complete
+	McCommandTimeout = 30 * time.Second
+	// McCommandOutputIdleTime ends the output of a console command once the console has been quiet for this time span
+	McCommandOutputIdleTime = 500 * time.Millisecond
+	// McSaveCompleteOutput is logged by the mc server once `save-all flush` has written the world
+	McSaveCompleteOutput = "Saved the game"
+
+	// ConsoleReplayLines is the default number of past log lines sent when a console connects
+	ConsoleReplayLines = 100
+	// ConsoleMaximumReplayLines limits the past log lines a console can request
+	ConsoleMaximumReplayLines = 1000
+	// ConsoleMaximumCommandLength limits the length of console commands
+	ConsoleMaximumCommandLength = 256
+	// LogDefaultTail is the number of log lines returned by a log search without `tail`
+	LogDefaultTail = 1000
+	// LogMaximumTail limits the log lines returned by a log search
+	LogMaximumTail = 10000
+	// LogArchivesPerServer limits the archived container logs of a server, the oldest archives are removed first
+	LogArchivesPerServer = 20
+)
@@ -0,0 +1,8 @@
+package config
+
+const (
+	// ServerFileMaximumBytes limits files read and written via the file api, e.g. configs and datapacks
+	ServerFileMaximumBytes int64 = 64 << 20
+	// ServerFileListMaximumEntries limits the entries of a listed directory
+	ServerFileListMaximumEntries = 10000
+)
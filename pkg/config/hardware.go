@@ -2,5 +2,11 @@ package config
 
 const DefaultRamSize = 1024
 
-// TODO make it dependend on system resources
+// MaximumRamPerInstance is the upper limit of ram per mc server. The effective limit also depends on the host memory
 const MaximumRamPerInstance = DefaultRamSize * 12 // 12GB
+
+// HostReservedRamMB is the memory which is kept free for the operating system, docker and InstantMC itself
+const HostReservedRamMB = 1024
+
+// MinimumFreeDiskMB is the disk space which needs to be free before a new mc world is created
+const MinimumFreeDiskMB = 2048
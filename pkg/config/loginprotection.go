@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+const (
+	// LoginFreeAttempts is the number of failed logins per username or client ip which don't lead to a lockout
+	LoginFreeAttempts = 3
+	// LoginBaseLockout is the lockout after the first failed login exceeding LoginFreeAttempts. It doubles with every further failure
+	LoginBaseLockout = 2 * time.Second
+	// LoginMaximumLockout limits the exponential growth of the lockout
+	LoginMaximumLockout = time.Hour
+	// LoginFailureResetTime forgets the failed logins of a username or client ip after this time span without a further failure
+	LoginFailureResetTime = 24 * time.Hour
+)
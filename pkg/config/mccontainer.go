@@ -1,6 +1,9 @@
 package config
 
-import "strconv"
+import (
+	"strconv"
+	"time"
+)
 
 const (
 	BaseImageName = "ghcr.io/instantmcorg/client"
@@ -10,6 +13,14 @@ const (
 	BaseVanillaMcImageName = BaseImageName + McVersionSuffix
 	ContainerBaseName      = "MC-Server-"
 	McServerProxyPort      = 25585
+
+	// McServerStopTimeout is the time in seconds a mc server gets to shut down gracefully before it is killed
+	McServerStopTimeout = 30
+
+	// McContainerStartAttempts is how often starting a mc server container is tried before its preparation fails
+	McContainerStartAttempts = 5
+	// McContainerStartRetryDelay is the pause between two attempts to start a mc server container
+	McContainerStartRetryDelay = 2 * time.Second
 )
 
 var (
@@ -3,4 +3,24 @@ package config
 const DataDir = "data"
 const McWorldsDir = "worlds"
 
+// ServerPropertiesFile is the configuration of a mc server, it's saved in the world directory
+const ServerPropertiesFile = "server.properties"
+
+// McLevelDir is the directory of the level inside the world directory, the mc server uses the default level-name
+const McLevelDir = "world"
+
+// McLogsDir contains the archived logs of removed containers in a directory per server
+const McLogsDir = "logs"
+
 const PasswordRequiresChange = "admin" // a summit of all passwords which are not allowed and need to be changed
+
+const MinimumPasswordLength = 10
+
+// AdminPasswordEnv is the environment variable which contains the initial admin password on the first start
+// If it isn't set, a random password is generated and printed to the log once
+const AdminPasswordEnv = "INSTANTMC_ADMIN_PASSWORD"
+
+const GeneratedAdminPasswordLength = 16
+
+// ForbiddenPasswords are too common to be accepted, regardless of their length
+var ForbiddenPasswords = []string{PasswordRequiresChange, "password", "password1", "1234567890", "0123456789", "qwertyuiop", "minecraft", "instantmc", "letmein123"}
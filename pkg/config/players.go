@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+// The player lists of a mc server are saved in its world directory
+const (
+	WhitelistFile     = "whitelist.json"
+	OpsFile           = "ops.json"
+	BannedPlayersFile = "banned-players.json"
+)
+
+const (
+	// OpDefaultLevel is the permission level of ops added to the ops.json of a stopped server
+	OpDefaultLevel = 4
+	// BanReasonMaximumLength limits the length of ban reasons
+	BanReasonMaximumLength = 200
+)
+
+// The mc server logs the failure of a player list command instead of returning an error, the console output is checked for these messages
+var (
+	// PlayerCommandUnknownPlayerOutputs are logged if no account has the player name
+	PlayerCommandUnknownPlayerOutputs = []string{"That player does not exist", "No player was found"}
+	// PlayerCommandNotListedOutputs are logged if the player to remove isn't on the list
+	PlayerCommandNotListedOutputs = []string{"Player is not whitelisted", "Nothing changed. The player is not an operator", "Nothing changed. The player isn't banned"}
+	// PlayerCommandAlreadyListedOutputs are logged if the player to add is already on the list, which isn't an error
+	PlayerCommandAlreadyListedOutputs = []string{"Player is already whitelisted", "Nothing changed. The player already is an operator", "Nothing changed. The player is already banned"}
+	// PlayerCommandFailedOutputs are logged by other failures, e.g. `Could not add Steve to the whitelist` of old mc versions
+	PlayerCommandFailedOutputs = []string{"Unknown or incomplete command", "Could not "}
+)
+
+// MojangProfileURL resolves the uuid of a player name, it's needed to add players to the lists of a stopped server
+const MojangProfileURL = "https://api.mojang.com/users/profiles/minecraft/%s"
+
+const MojangAPITimeout = 5 * time.Second
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+const (
+	// DefaultPoolSize is the amount of prepared containers of the latest mc version if no pool targets have ever been configured
+	DefaultPoolSize = 1
+	// MaximumPoolSizePerTarget limits the amount of prepared containers per mc version and ram size
+	MaximumPoolSizePerTarget = 10
+	// PoolCheckInterval defines how often the prepared server pool is checked for missing containers
+	PoolCheckInterval = time.Minute
+
+	// AutoscaleEnabled adds prepared containers to the pool based on the recent start requests
+	AutoscaleEnabled = true
+	// AutoscaleWindow is the time span of start requests which is considered for autoscaling and statistics
+	AutoscaleWindow = 7 * 24 * time.Hour
+	// AutoscaleIdleTimeout removes autoscaled containers of a mc version and ram size which hasn't been requested for this time span
+	AutoscaleIdleTimeout = 3 * 24 * time.Hour
+	// AutoscaleRequestsPerContainer defines how many start requests within AutoscaleWindow add one prepared container
+	AutoscaleRequestsPerContainer = 5
+)
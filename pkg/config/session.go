@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+const (
+	// SessionIdleTimeout ends a session which hasn't been used for this time span
+	SessionIdleTimeout = 7 * 24 * time.Hour
+	// SessionAbsoluteTimeout ends every session after this time span, regardless of its usage
+	SessionAbsoluteTimeout = 30 * 24 * time.Hour
+	// SessionTouchInterval limits how often the last usage of a session is written to the db
+	SessionTouchInterval = time.Minute
+	// SessionSweepInterval defines how often expired sessions are purged from the db
+	SessionSweepInterval = time.Hour
+)
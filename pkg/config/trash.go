@@ -0,0 +1,13 @@
+package config
+
+import "time"
+
+// McTrashDir contains the worlds of deleted servers in a directory per server until they are purged
+const McTrashDir = "trash"
+
+const (
+	// DeletedServerGracePeriod defines how long a deleted server can be undeleted, its port stays reserved meanwhile
+	DeletedServerGracePeriod = 7 * 24 * time.Hour
+	// TrashPurgeInterval defines how often the deleted servers whose grace period expired are purged
+	TrashPurgeInterval = time.Hour
+)
@@ -0,0 +1,10 @@
+package config
+
+const (
+	// WorldUploadMaximumBytes limits the size of an uploaded world archive
+	WorldUploadMaximumBytes int64 = 2 << 30
+	// WorldUploadMaximumExtractedBytes limits the size of the extracted world, e.g. against zip bombs
+	WorldUploadMaximumExtractedBytes int64 = 8 << 30
+	// WorldUploadMaximumFiles limits the number of files and directories of an uploaded world
+	WorldUploadMaximumFiles = 100000
+)
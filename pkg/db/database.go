@@ -1,19 +1,25 @@
 package db
 
 import (
+	"errors"
 	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/enums"
 	"github.com/instantmc/server/pkg/models"
 	"github.com/instantmc/server/pkg/utils"
 	"github.com/rs/zerolog/log"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"os"
 	"path/filepath"
+	"time"
 )
 
 var db *gorm.DB
 
 const sessionTokenLength = 32
 
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 func Init() {
 	dbPath := filepath.Join(config.DataDir, "data.db")
 	dbConnection, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
@@ -27,39 +33,109 @@ func Init() {
 	// Migrate schemas
 	db.AutoMigrate(&models.User{})
 	db.AutoMigrate(&models.Session{})
+	db.AutoMigrate(&models.APIToken{})
+	db.AutoMigrate(&models.LoginFailure{})
 	db.AutoMigrate(&models.DBMcServerContainer{})
+	db.AutoMigrate(&models.DBServerStateTransition{})
+	db.AutoMigrate(&models.DBPoolTarget{})
+	db.AutoMigrate(&models.DBWorldProfile{})
+	db.AutoMigrate(&models.DBBackup{})
+	db.AutoMigrate(&models.DBBackupSchedule{})
+	db.AutoMigrate(&models.DBStartRequest{})
+	db.AutoMigrate(&models.AuditEntry{})
 
+	if err := migrateLegacyAdmins(); err != nil {
+		log.Fatal().Err(err).Msg("Couldn't migrate the roles of admin users")
+	}
 	if err := createDefaultAdminUserIfNeeded(); err != nil {
 		log.Fatal().Err(err).Msg("Couldn't create default admin user")
 	}
 }
 
+// migrateLegacyAdmins Changes the role of users saved as enums.LegacyAdmin to enums.Admin
+// The zero value has no permissions since then, it's never saved anymore
+func migrateLegacyAdmins() error {
+	return db.Unscoped().Model(&models.User{}).Where("role = ?", enums.LegacyAdmin).Update("role", enums.Admin).Error
+}
+
+// createDefaultAdminUserIfNeeded Creates the user `admin` on the first start
+// The password is read from config.AdminPasswordEnv or generated and printed to the log once
+// Existing users with the former default password `admin` need to change their password
 func createDefaultAdminUserIfNeeded() error {
 	var users []models.User
 	err := db.Find(&users).Error
-	if len(users) == 0 {
-		err = db.Create(&models.User{Username: "admin", Password: utils.SHA256([]byte("admin"))}).Error
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 {
+		for _, user := range users {
+			if matches, _ := utils.VerifyPassword(config.PasswordRequiresChange, user.Password); matches && !user.MustChangePassword {
+				if err := SetMustChangePassword(&user, true); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	}
 
-	return err
+	password := os.Getenv(config.AdminPasswordEnv)
+	mustChangePassword := utils.CheckPasswordPolicy(password, "admin") != nil
+	if password == "" {
+		password, err = utils.SecureRandomString(config.GeneratedAdminPasswordLength)
+		if err != nil {
+			return err
+		}
+		// the password has been written to the log, so it must not stay in use
+		mustChangePassword = true
+		log.Warn().Msgf("Created the user \"admin\" with the password \"%s\". You need to change the password after the first login. This message won't be shown again", password)
+	}
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return db.Create(&models.User{Username: "admin", Password: passwordHash, Role: enums.Admin, MustChangePassword: mustChangePassword}).Error
 }
 
-// Login searches and returns a ´models.User´ struct if username and the sha256 password matches a record otherwise returns an error
+// Login searches and returns a ´models.User´ struct if username and password match a record otherwise returns an error
+// Password hashes of an outdated format are replaced on the fly
 func Login(username string, password string) (models.User, error) {
 	// check if user exists
 	var user models.User
-	err := db.First(&user, "username = ? AND password = ?", username, password).Error
-	return user, err
+	if err := db.First(&user, "username = ?", username).Error; err != nil {
+		// the hash is calculated anyway, otherwise the response time reveals if the username exists
+		utils.HashPassword(password)
+		return user, err
+	}
+	matches, needsRehash := utils.VerifyPassword(password, user.Password)
+	if !matches {
+		return user, ErrInvalidCredentials
+	}
+	if needsRehash {
+		passwordHash, err := utils.HashPassword(password)
+		if err == nil {
+			user.Password = passwordHash
+			err = db.Save(&user).Error
+		}
+		if err != nil {
+			log.Warn().Err(err).Msgf("Couldn't upgrade password hash of user %s", username)
+		}
+	}
+	return user, nil
 }
 
 // CreateSession Creates a session and returns the token
 // If not successful an error is returned
-func CreateSession(userModel *models.User) (string, error) {
+func CreateSession(userModel *models.User, clientIP string, userAgent string) (string, error) {
 	sessionToken := utils.RandomString(sessionTokenLength)
+	now := time.Now()
 	err := db.Create(&models.Session{
-		Token:  utils.SHA256([]byte(sessionToken)),
-		UserID: int(userModel.ID),
-		User:   *userModel,
+		Token:      utils.SHA256([]byte(sessionToken)),
+		UserID:     int(userModel.ID),
+		User:       *userModel,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(config.SessionAbsoluteTimeout),
+		ClientIP:   clientIP,
+		UserAgent:  userAgent,
 	}).Error
 	return sessionToken, err
 }
@@ -67,10 +143,144 @@ func CreateSession(userModel *models.User) (string, error) {
 func GetSession(token string) (models.Session, error) {
 	var session models.Session
 	token = utils.SHA256([]byte(token))
-	err := db.First(&session, "token = ?", token).Error
+	err := db.Preload("User").First(&session, "token = ?", token).Error
 	return session, err
 }
 
+// TouchSession Updates the last usage of the session. The db is written at most once per config.SessionTouchInterval
+func TouchSession(session *models.Session) error {
+	now := time.Now()
+	if now.Sub(session.LastUsedAt) < config.SessionTouchInterval {
+		return nil
+	}
+	session.LastUsedAt = now
+	return db.Model(session).Update("last_used_at", now).Error
+}
+
+func GetSessionsOfUser(user *models.User) ([]models.Session, error) {
+	var result []models.Session
+	err := db.Order("id").Find(&result, "user_id = ?", user.ID).Error
+	return result, err
+}
+
+// DeleteSession Deletes the session with given ID if it belongs to the user
+func DeleteSession(user *models.User, sessionID uint) error {
+	result := db.Delete(&models.Session{}, "id = ? AND user_id = ?", sessionID, user.ID)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// CreateAPIToken Creates an API token for the user and returns the token. Only its hash is saved
+func CreateAPIToken(apiToken *models.APIToken) (string, error) {
+	randomPart, err := utils.SecureRandomString(config.APITokenLength)
+	if err != nil {
+		return "", err
+	}
+	token := config.APITokenPrefix + randomPart
+	apiToken.Token = utils.SHA256([]byte(token))
+	err = db.Create(apiToken).Error
+	return token, err
+}
+
+func GetAPIToken(token string) (models.APIToken, error) {
+	var apiToken models.APIToken
+	token = utils.SHA256([]byte(token))
+	err := db.Preload("User").First(&apiToken, "token = ?", token).Error
+	return apiToken, err
+}
+
+func GetAPITokensOfUser(user *models.User) ([]models.APIToken, error) {
+	var result []models.APIToken
+	err := db.Order("id").Find(&result, "user_id = ?", user.ID).Error
+	return result, err
+}
+
+// TouchAPIToken Updates the last usage of the token. The db is written at most once per config.SessionTouchInterval
+func TouchAPIToken(apiToken *models.APIToken) error {
+	now := time.Now()
+	if now.Sub(apiToken.LastUsedAt) < config.SessionTouchInterval {
+		return nil
+	}
+	apiToken.LastUsedAt = now
+	return db.Model(apiToken).Update("last_used_at", now).Error
+}
+
+// DeleteAPIToken Deletes the token with given ID if it belongs to the user
+func DeleteAPIToken(user *models.User, tokenID uint) error {
+	result := db.Unscoped().Delete(&models.APIToken{}, "id = ? AND user_id = ?", tokenID, user.ID)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// GetLoginFailure Returns the failed logins of the subject. A new unsaved record is returned if there is none
+func GetLoginFailure(kind enums.LoginFailureKind, subject string) (models.LoginFailure, error) {
+	var failure models.LoginFailure
+	err := db.First(&failure, "kind = ? AND subject = ?", kind, subject).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.LoginFailure{Kind: kind, Subject: subject}, nil
+	}
+	return failure, err
+}
+
+func SaveLoginFailure(failure *models.LoginFailure) error {
+	return db.Save(failure).Error
+}
+
+func GetLoginFailures() ([]models.LoginFailure, error) {
+	var result []models.LoginFailure
+	err := db.Order("last_failure_at desc").Find(&result).Error
+	return result, err
+}
+
+// DeleteLoginFailure Removes the failed logins with given ID which also lifts its lockout
+func DeleteLoginFailure(failureID uint) error {
+	result := db.Unscoped().Delete(&models.LoginFailure{}, "id = ?", failureID)
+	if result.Error == nil && result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return result.Error
+}
+
+// DeleteLoginFailuresOf Forgets the failed logins of the subject
+func DeleteLoginFailuresOf(kind enums.LoginFailureKind, subject string) error {
+	return db.Unscoped().Delete(&models.LoginFailure{}, "kind = ? AND subject = ?", kind, subject).Error
+}
+
+// DeleteStaleLoginFailures Removes all failed logins which are old enough to be forgotten
+func DeleteStaleLoginFailures() (int64, error) {
+	now := time.Now()
+	result := db.Unscoped().Delete(&models.LoginFailure{}, "locked_until < ? AND last_failure_at < ?", now, now.Add(-config.LoginFailureResetTime))
+	return result.RowsAffected, result.Error
+}
+
+func RevokeSession(session *models.Session) error {
+	return db.Delete(session).Error
+}
+
+// DeleteExpiredSessions Removes all sessions which are expired by their idle or absolute timeout or have been revoked
+func DeleteExpiredSessions() (int64, error) {
+	now := time.Now()
+	var sessions []models.Session
+	if err := db.Unscoped().Find(&sessions).Error; err != nil {
+		return 0, err
+	}
+	var expiredIDs []uint
+	for _, session := range sessions {
+		if session.DeletedAt.Valid || session.IsExpired(now) {
+			expiredIDs = append(expiredIDs, session.ID)
+		}
+	}
+	if len(expiredIDs) == 0 {
+		return 0, nil
+	}
+	result := db.Unscoped().Delete(&models.Session{}, expiredIDs)
+	return result.RowsAffected, result.Error
+}
+
 func GetUserFromToken(token string) (models.User, error) {
 	var session models.Session
 	token = utils.SHA256([]byte(token))
@@ -79,18 +289,89 @@ func GetUserFromToken(token string) (models.User, error) {
 }
 
 // UpdatePassword updates the password of the target user and deletes all sessions from this user
+// A required password change is fulfilled by this
 func UpdatePassword(user *models.User, newPassword string) error {
-	hashedNewPassword := utils.SHA256([]byte(newPassword))
+	hashedNewPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
 	user.Password = hashedNewPassword
-	err := db.Save(&user).Error
+	user.MustChangePassword = false
+	err = db.Save(&user).Error
 	if err != nil {
 		return err
 	}
 	return db.Delete(&models.Session{}, "user_id = ?", user.ID).Error
 }
 
-func AddMcServerContainer(user *models.User, mcContainer *models.McServerContainer) error {
-	return db.Create(&models.DBMcServerContainer{UserID: int(user.ID), McServerContainer: *mcContainer}).Error
+func GetUsers() ([]models.User, error) {
+	var result []models.User
+	err := db.Order("id").Find(&result).Error
+	return result, err
+}
+
+func GetUser(userID uint) (models.User, error) {
+	var user models.User
+	err := db.First(&user, userID).Error
+	return user, err
+}
+
+func UsernameExists(username string) bool {
+	var count int64
+	db.Model(&models.User{}).Where("username = ?", username).Count(&count)
+	return count > 0
+}
+
+// CountUsersWithRole Returns the amount of users with given role
+func CountUsersWithRole(role enums.UserRole) (int64, error) {
+	var count int64
+	err := db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+// CreateUser Creates a user with a salted hash of the password
+// The user needs to change the password after the first login because the creator knows it
+func CreateUser(username string, password string, role enums.UserRole) (models.User, error) {
+	passwordHash, err := utils.HashPassword(password)
+	if err != nil {
+		return models.User{}, err
+	}
+	user := models.User{Username: username, Password: passwordHash, Role: role, MustChangePassword: true}
+	err = db.Create(&user).Error
+	return user, err
+}
+
+func SetMustChangePassword(user *models.User, mustChangePassword bool) error {
+	user.MustChangePassword = mustChangePassword
+	return db.Model(user).Update("must_change_password", mustChangePassword).Error
+}
+
+func UpdateUserRole(user *models.User, role enums.UserRole) error {
+	user.Role = role
+	return db.Save(user).Error
+}
+
+// DeleteUser deletes the user and all sessions and API tokens of the user
+// The servers of the user, including the ones in the trash, are handed over to newOwner
+func DeleteUser(user *models.User, newOwner *models.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.DBMcServerContainer{}).Where("user_id = ?", user.ID).Update("user_id", newOwner.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&models.Session{}, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.APIToken{}, "user_id = ?", user.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+}
+
+func AddMcServerContainer(user *models.User, mcContainer *models.McServerContainer) (models.DBMcServerContainer, error) {
+	result := models.DBMcServerContainer{UserID: int(user.ID), McServerContainer: *mcContainer}
+	err := db.Create(&result).Error
+	return result, err
 }
 
 func GetSavedMcServer() ([]models.DBMcServerContainer, error) {
@@ -99,12 +380,61 @@ func GetSavedMcServer() ([]models.DBMcServerContainer, error) {
 	return result, err
 }
 
+func GetSavedMcServerOfUser(user *models.User) ([]models.DBMcServerContainer, error) {
+	var result []models.DBMcServerContainer
+	err := db.Find(&result, "user_id = ?", user.ID).Error
+	return result, err
+}
+
+func GetSavedMcServerByStatus(status ...enums.ServerStatus) ([]models.DBMcServerContainer, error) {
+	var result []models.DBMcServerContainer
+	err := db.Find(&result, "status IN ?", status).Error
+	return result, err
+}
+
 func GetMcServerData(serverID string) (models.DBMcServerContainer, error) {
 	var result models.DBMcServerContainer
 	err := db.First(&result, "server_id = ?", serverID).Error
 	return result, err
 }
 
+// GetDeletedMcServers Returns the servers in the trash, which can still be undeleted
+func GetDeletedMcServers() ([]models.DBMcServerContainer, error) {
+	var result []models.DBMcServerContainer
+	err := db.Unscoped().Order("deleted_at DESC").Find(&result, "deleted_at IS NOT NULL AND status = ?", enums.Deleted).Error
+	return result, err
+}
+
+func GetDeletedMcServer(serverID string) (models.DBMcServerContainer, error) {
+	var result models.DBMcServerContainer
+	err := db.Unscoped().First(&result, "server_id = ? AND deleted_at IS NOT NULL AND status = ?", serverID, enums.Deleted).Error
+	return result, err
+}
+
+// UndeleteServer Removes the soft delete mark of the server
+func UndeleteServer(mcServerContainerModel *models.DBMcServerContainer) error {
+	mcServerContainerModel.DeletedAt = gorm.DeletedAt{}
+	return db.Unscoped().Model(mcServerContainerModel).Update("deleted_at", nil).Error
+}
+
+// SoftDeleteServer Saves the status of the server and its state transition and marks it as deleted in one transaction
+func SoftDeleteServer(mcServerContainerModel *models.DBMcServerContainer, status enums.ServerStatus, transition *models.DBServerStateTransition) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(mcServerContainerModel).Update("status", status).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(transition).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(mcServerContainerModel).Error; err != nil {
+			return err
+		}
+		mcServerContainerModel.Status = status
+		return nil
+	})
+}
+
+// DeleteServer Marks the server as deleted, the row is kept for its history
 func DeleteServer(mcServerContainerModel *models.DBMcServerContainer) error {
 	return db.Delete(&mcServerContainerModel).Error
 }
@@ -116,3 +446,170 @@ func UpdateServerContainerID(mcServerContainerModel *models.DBMcServerContainer,
 	mcServerContainerModel.ContainerID = newContainerID
 	return db.Save(&mcServerContainerModel).Error
 }
+
+// UpdateServerStatus Saves the status of the server, deleted servers included
+func UpdateServerStatus(mcServerContainerModel *models.DBMcServerContainer, status enums.ServerStatus) error {
+	mcServerContainerModel.Status = status
+	return db.Unscoped().Save(&mcServerContainerModel).Error
+}
+
+func AddServerStateTransition(transition *models.DBServerStateTransition) error {
+	return db.Create(transition).Error
+}
+
+// GetServerStateHistory Returns all state transitions of the server, oldest first
+func GetServerStateHistory(serverID string) ([]models.DBServerStateTransition, error) {
+	var result []models.DBServerStateTransition
+	err := db.Order("id").Find(&result, "server_id = ?", serverID).Error
+	return result, err
+}
+
+func GetPoolTargets() ([]models.DBPoolTarget, error) {
+	var result []models.DBPoolTarget
+	err := db.Find(&result).Error
+	return result, err
+}
+
+// SetPoolTarget Creates or updates the pool target with the same mc version, ram size and world profile
+// A target with a count of 0 is kept, so a disabled default target isn't created again on the next start
+func SetPoolTarget(target models.PoolTarget) error {
+	var existing models.DBPoolTarget
+	err := db.First(&existing, "mc_version = ? AND ram_size_mb = ? AND profile = ?", target.McVersion, target.RamSizeMB, target.Profile).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	existing.PoolTarget = target
+	return db.Save(&existing).Error
+}
+
+// DeletePoolTargetsOfProfile Removes the pool targets of a deleted world profile
+func DeletePoolTargetsOfProfile(profileName string) error {
+	return db.Unscoped().Where("profile = ?", profileName).Delete(&models.DBPoolTarget{}).Error
+}
+
+func GetWorldProfiles() ([]models.DBWorldProfile, error) {
+	var result []models.DBWorldProfile
+	err := db.Order("name").Find(&result).Error
+	return result, err
+}
+
+func GetWorldProfile(name string) (models.DBWorldProfile, error) {
+	var profile models.DBWorldProfile
+	err := db.First(&profile, "name = ?", name).Error
+	return profile, err
+}
+
+// SetWorldProfile Creates or updates the world profile with the same name
+func SetWorldProfile(profile models.WorldProfile) error {
+	var existing models.DBWorldProfile
+	err := db.First(&existing, "name = ?", profile.Name).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	existing.WorldProfile = profile
+	return db.Save(&existing).Error
+}
+
+func DeleteWorldProfile(name string) error {
+	return db.Unscoped().Where("name = ?", name).Delete(&models.DBWorldProfile{}).Error
+}
+
+func AddStartRequest(request *models.DBStartRequest) error {
+	return db.Create(request).Error
+}
+
+func GetStartRequestsSince(since time.Time) ([]models.DBStartRequest, error) {
+	var result []models.DBStartRequest
+	err := db.Find(&result, "created_at >= ?", since).Error
+	return result, err
+}
+
+func DeleteStartRequestsBefore(before time.Time) error {
+	return db.Unscoped().Delete(&models.DBStartRequest{}, "created_at < ?", before).Error
+}
+
+func AddAuditEntry(entry *models.AuditEntry) error {
+	return db.Create(entry).Error
+}
+
+// GetAuditEntries Returns the audit entries matching the filter, oldest first
+func GetAuditEntries(filter models.AuditFilter) ([]models.AuditEntry, error) {
+	query := db.Order("id")
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.ServerID != "" {
+		query = query.Where("server_id = ?", filter.ServerID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at <= ?", filter.Until)
+	}
+	var result []models.AuditEntry
+	err := query.Find(&result).Error
+	return result, err
+}
+
+func AddBackup(backup *models.DBBackup) error {
+	return db.Create(backup).Error
+}
+
+// GetBackups Returns the backups of the server, newest first
+func GetBackups(serverID string) ([]models.DBBackup, error) {
+	var result []models.DBBackup
+	err := db.Order("created_at DESC").Find(&result, "server_id = ?", serverID).Error
+	return result, err
+}
+
+func GetBackup(serverID string, backupID uint) (models.DBBackup, error) {
+	var backup models.DBBackup
+	err := db.First(&backup, "server_id = ? AND id = ?", serverID, backupID).Error
+	return backup, err
+}
+
+// GetBackupByID Returns the backup regardless of its server, backups are kept after their server is deleted
+func GetBackupByID(backupID uint) (models.DBBackup, error) {
+	var backup models.DBBackup
+	err := db.First(&backup, backupID).Error
+	return backup, err
+}
+
+func DeleteBackup(backup *models.DBBackup) error {
+	return db.Unscoped().Delete(backup).Error
+}
+
+func GetBackupSchedules() ([]models.DBBackupSchedule, error) {
+	var result []models.DBBackupSchedule
+	err := db.Find(&result).Error
+	return result, err
+}
+
+func GetBackupSchedule(serverID string) (models.DBBackupSchedule, error) {
+	var schedule models.DBBackupSchedule
+	err := db.First(&schedule, "server_id = ?", serverID).Error
+	return schedule, err
+}
+
+// SetBackupSchedule Creates or updates the backup schedule of the server
+func SetBackupSchedule(serverID string, cron string, retention models.BackupRetention) (models.DBBackupSchedule, error) {
+	var existing models.DBBackupSchedule
+	err := db.First(&existing, "server_id = ?", serverID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return existing, err
+	}
+	existing.ServerID = serverID
+	existing.Cron = cron
+	existing.BackupRetention = retention
+	return existing, db.Save(&existing).Error
+}
+
+func UpdateBackupScheduleLastRun(schedule *models.DBBackupSchedule, lastRunAt time.Time) error {
+	schedule.LastRunAt = &lastRunAt
+	return db.Model(schedule).Update("last_run_at", lastRunAt).Error
+}
+
+func DeleteBackupSchedule(serverID string) error {
+	return db.Unscoped().Where("server_id = ?", serverID).Delete(&models.DBBackupSchedule{}).Error
+}
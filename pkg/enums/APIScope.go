@@ -0,0 +1,17 @@
+package enums
+
+type APIScope string
+
+// Scopes of API tokens. Each route which can be accessed with an API token requires exactly one scope
+const (
+	ScopeServerRead    APIScope = "server:read"
+	ScopeServerStart   APIScope = "server:start"
+	ScopeServerStop    APIScope = "server:stop"
+	ScopeServerDelete  APIScope = "server:delete"
+	ScopeServerConsole APIScope = "server:console"
+	ScopeServerConfig  APIScope = "server:config"
+	ScopeServerBackup  APIScope = "server:backup"
+	ScopeStatsRead     APIScope = "stats:read"
+)
+
+var AllAPIScopes = []APIScope{ScopeServerRead, ScopeServerStart, ScopeServerStop, ScopeServerDelete, ScopeServerConsole, ScopeServerConfig, ScopeServerBackup, ScopeStatsRead}
@@ -0,0 +1,9 @@
+package enums
+
+type LoginFailureKind string
+
+// Failed logins are counted per username and per client ip
+const (
+	LoginFailureUsername LoginFailureKind = "username"
+	LoginFailureIP       LoginFailureKind = "ip"
+)
@@ -2,11 +2,20 @@ package enums
 
 type ServerStatus int
 
+// The values are persisted in the db, new states must be appended
 const (
 	Prepared ServerStatus = iota
 	Stopped
 	Preparing
 	Running
+	Starting
+	Stopping
+	Crashed
+	Deleting
+	// Deleted servers are in the trash until their grace period expires
+	Deleted
+	// Purged servers have been deleted permanently
+	Purged
 )
 
 func (s ServerStatus) String() string {
@@ -19,6 +28,18 @@ func (s ServerStatus) String() string {
 		return "Prepared"
 	case Running:
 		return "Running"
+	case Starting:
+		return "Starting"
+	case Stopping:
+		return "Stopping"
+	case Crashed:
+		return "Crashed"
+	case Deleting:
+		return "Deleting"
+	case Deleted:
+		return "Deleted"
+	case Purged:
+		return "Purged"
 	}
 	return "unknown"
 }
@@ -0,0 +1,54 @@
+package enums
+
+import "errors"
+
+type UserRole int
+
+// The values are persisted in the db. The zero value has no permissions, so a user which hasn't been loaded can't do anything
+const (
+	// LegacyAdmin is the value admins were saved with before, db.Init migrates them to Admin
+	LegacyAdmin UserRole = iota
+	Operator
+	Viewer
+	Admin
+)
+
+func (r UserRole) String() string {
+	switch r {
+	case Admin:
+		return "admin"
+	case Operator:
+		return "operator"
+	case Viewer:
+		return "viewer"
+	}
+	return "unknown"
+}
+
+// IsAtLeast Returns true if the role has all permissions of the other role
+// Admins can do everything, operators can control their own servers and viewers can only look at them
+// Unknown roles including the zero value never have the permissions of another role
+func (r UserRole) IsAtLeast(other UserRole) bool {
+	return r.rank() > 0 && other.rank() > 0 && r.rank() >= other.rank()
+}
+
+func (r UserRole) rank() int {
+	switch r {
+	case Admin:
+		return 3
+	case Operator:
+		return 2
+	case Viewer:
+		return 1
+	}
+	return 0
+}
+
+func ParseUserRole(role string) (UserRole, error) {
+	for _, curRole := range []UserRole{Admin, Operator, Viewer} {
+		if curRole.String() == role {
+			return curRole, nil
+		}
+	}
+	return Viewer, errors.New("unknown role " + role)
+}
@@ -0,0 +1,34 @@
+package enums
+
+import "testing"
+
+func TestUserRoleIsAtLeast(t *testing.T) {
+	if !Admin.IsAtLeast(Operator) || !Admin.IsAtLeast(Viewer) {
+		t.Errorf("Admin should have all permissions")
+	}
+	if !Operator.IsAtLeast(Viewer) || Operator.IsAtLeast(Admin) {
+		t.Errorf("Operator should have more permissions than viewer but less than admin")
+	}
+	if Viewer.IsAtLeast(Operator) {
+		t.Errorf("Viewer shouldn't have operator permissions")
+	}
+	var zero UserRole
+	if zero.IsAtLeast(Viewer) || UserRole(42).IsAtLeast(Viewer) {
+		t.Errorf("Unknown roles shouldn't have any permissions")
+	}
+	if Admin.IsAtLeast(zero) {
+		t.Errorf("Unknown roles shouldn't be satisfied by any role")
+	}
+}
+
+func TestParseUserRole(t *testing.T) {
+	for _, role := range []UserRole{Admin, Operator, Viewer} {
+		parsed, err := ParseUserRole(role.String())
+		if err != nil || parsed != role {
+			t.Errorf("Parsing %s returned %s", role, parsed)
+		}
+	}
+	if _, err := ParseUserRole("root"); err == nil {
+		t.Errorf("Parsing an unknown role should fail")
+	}
+}
@@ -0,0 +1,12 @@
+package enums
+
+type WorldUploadStage string
+
+// A world upload is received, extracted and then replaces the level of the server
+const (
+	UploadReceiving  WorldUploadStage = "receiving"
+	UploadExtracting WorldUploadStage = "extracting"
+	UploadReplacing  WorldUploadStage = "replacing"
+	UploadDone       WorldUploadStage = "done"
+	UploadFailed     WorldUploadStage = "failed"
+)
@@ -0,0 +1,214 @@
+package manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const memInfoPath = "/proc/meminfo"
+
+var ErrInsufficientMemory = errors.New("not enough memory available")
+var ErrInsufficientStorage = errors.New("not enough disk space available")
+
+// admissionMutex prevents that two concurrent admissions allocate the same free memory
+var admissionMutex sync.Mutex
+
+// memoryReservations holds the ram of admitted servers until they are saved in an allocatingStatus, map[serverID]ram size in mb
+var memoryReservations = map[string]int{}
+var memoryReservationsMutex sync.Mutex
+
+// allocatingStatus are the server states in which a server holds its ram
+var allocatingStatus = []enums.ServerStatus{enums.Preparing, enums.Starting, enums.Running, enums.Stopping}
+
+// GetHostResources Reads the host memory from /proc/meminfo, the cpus from the docker daemon and the disk space of config.DataDir
+// The memory allocated to mc server containers is the sum of the ram sizes of running and prepared containers
+func GetHostResources() (models.HostResources, error) {
+	var resources models.HostResources
+
+	dockerInfo, err := GetDockerInfo()
+	if err != nil {
+		return resources, err
+	}
+	resources.CPUs = dockerInfo.NCPU
+
+	memTotal, memAvailable, err := readMemInfo()
+	if err != nil {
+		// e.g. not running on linux. The docker daemon knows the total memory at least
+		log.Warn().Err(err).Msgf("Couldn't read %s", memInfoPath)
+		memTotal = int(dockerInfo.MemTotal / 1000 / 1000)
+		memAvailable = memTotal
+	}
+	resources.MemoryTotalMB = memTotal
+	resources.MemoryAvailableMB = memAvailable
+	resources.MemoryCapacityMB = memTotal - config.HostReservedRamMB
+	if resources.MemoryCapacityMB < 0 {
+		resources.MemoryCapacityMB = 0
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(config.DataDir, &stat); err != nil {
+		return resources, err
+	}
+	resources.DiskTotalMB = int(uint64(stat.Blocks) * uint64(stat.Bsize) / 1000 / 1000)
+	resources.DiskFreeMB = int(uint64(stat.Bavail) * uint64(stat.Bsize) / 1000 / 1000)
+
+	savedServer, err := db.GetSavedMcServerByStatus(allocatingStatus...)
+	if err != nil {
+		return resources, err
+	}
+	for _, server := range savedServer {
+		resources.MemoryAllocatedMB += server.RamSizeMB
+	}
+
+	memoryReservationsMutex.Lock()
+	for _, ramSize := range memoryReservations {
+		resources.MemoryReservedMB += ramSize
+	}
+	memoryReservationsMutex.Unlock()
+	resources.MemoryAllocatedMB += resources.MemoryReservedMB
+
+	// includes containers which are currently being prepared for the pool
+	preparedContainer, err := ListContainersByNameStart(config.WaitingReadyContainerName)
+	if err != nil {
+		return resources, err
+	}
+	for _, container := range preparedContainer {
+		ramSize, err := GetContainerRamSizeEnv(container.ID)
+		if err != nil {
+			continue
+		}
+		resources.MemoryPreparedMB += ramSize
+	}
+	resources.MemoryAllocatedMB += resources.MemoryPreparedMB
+
+	return resources, nil
+}
+
+// GetMaximumRamPerInstance Returns the maximum ram size of a mc server, limited by config.MaximumRamPerInstance and the host memory
+func GetMaximumRamPerInstance() int {
+	resources, err := GetHostResources()
+	if err != nil || resources.MemoryCapacityMB > config.MaximumRamPerInstance {
+		return config.MaximumRamPerInstance
+	}
+	return resources.MemoryCapacityMB
+}
+
+// AdmitMcServer Checks if the host has enough memory and disk space for a new mc server container with given ram size
+// Prepared containers are removed if they block the memory needed. They are prepared again by the pool as soon as there is enough memory
+// The memory of an admitted server is reserved until ReleaseMcServerAdmission is called, which has to happen once the server
+// is saved in a state which allocates its ram or once its start failed
+// Returns an error wrapping ErrInsufficientMemory or ErrInsufficientStorage if the server can't be admitted
+func AdmitMcServer(serverID string, ramSizeMB int) error {
+	admissionMutex.Lock()
+	defer admissionMutex.Unlock()
+
+	if err := checkAdmission(ramSizeMB); err != nil {
+		return err
+	}
+	memoryReservationsMutex.Lock()
+	memoryReservations[serverID] = ramSizeMB
+	memoryReservationsMutex.Unlock()
+	return nil
+}
+
+// ReleaseMcServerAdmission Frees the memory reserved by AdmitMcServer
+func ReleaseMcServerAdmission(serverID string) {
+	memoryReservationsMutex.Lock()
+	delete(memoryReservations, serverID)
+	memoryReservationsMutex.Unlock()
+}
+
+// checkAdmission Checks the host resources for AdmitMcServer, admissionMutex has to be locked
+func checkAdmission(ramSizeMB int) error {
+	resources, err := GetHostResources()
+	if err != nil {
+		return err
+	}
+	if resources.DiskFreeMB < config.MinimumFreeDiskMB {
+		return fmt.Errorf("%w: %dmb free, %dmb required", ErrInsufficientStorage, resources.DiskFreeMB, config.MinimumFreeDiskMB)
+	}
+
+	missingMemory := resources.MemoryAllocatedMB + ramSizeMB - resources.MemoryCapacityMB
+	if missingMemory <= 0 {
+		return nil
+	}
+	if missingMemory > resources.MemoryPreparedMB {
+		return fmt.Errorf("%w: %dmb free, %dmb requested", ErrInsufficientMemory, resources.MemoryCapacityMB-resources.MemoryAllocatedMB, ramSizeMB)
+	}
+
+	// a real user request is more important than a prepared container
+	preparedContainer, err := GetMcServerContainer(models.McContainerSearchConfig{Status: enums.Prepared})
+	if err != nil {
+		return err
+	}
+	for _, container := range preparedContainer {
+		if missingMemory <= 0 {
+			break
+		}
+		ramSize, err := GetContainerRamSizeEnv(container.ID)
+		if err != nil {
+			continue
+		}
+		log.Info().Msgf("Removing prepared container %s to free %dmb of memory", container.ID, ramSize)
+		if err := RemovePreparedContainer(container); err != nil {
+			log.Error().Err(err).Msgf("Couldn't remove prepared container %s", container.ID)
+			continue
+		}
+		missingMemory -= ramSize
+	}
+	if missingMemory > 0 {
+		return fmt.Errorf("%w: %dmb requested", ErrInsufficientMemory, ramSizeMB)
+	}
+	return nil
+}
+
+// hasCapacityFor Returns true if a container with given ram size fits into the host memory without removing other containers
+func hasCapacityFor(ramSizeMB int) bool {
+	resources, err := GetHostResources()
+	if err != nil {
+		log.Warn().Err(err).Msg("Couldn't read host resources")
+		return false
+	}
+	return resources.MemoryAllocatedMB+ramSizeMB <= resources.MemoryCapacityMB && resources.DiskFreeMB >= config.MinimumFreeDiskMB
+}
+
+// readMemInfo Returns the total and the available memory in mb
+func readMemInfo() (int, int, error) {
+	file, err := os.Open(memInfoPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	values := map[string]int{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// line format: "MemTotal:       16318412 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = value
+	}
+	memTotal, ok := values["MemTotal"]
+	if !ok {
+		return 0, 0, errors.New("MemTotal not found")
+	}
+	// the values are in KiB, ram sizes of mc server are in mb
+	return memTotal * 1024 / 1000 / 1000, values["MemAvailable"] * 1024 / 1000 / 1000, scanner.Err()
+}
@@ -0,0 +1,384 @@
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/instantmc/server/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWorldBusy             = errors.New("a backup or restore of the world is already in progress")
+	ErrBackupNotFound        = errors.New("backup not found")
+	ErrInvalidBackupSchedule = errors.New("invalid backup schedule")
+)
+
+// backupFileExtension is the extension of the gzip compressed tar archives of the world directory
+const backupFileExtension = ".tar.gz"
+
+// busyWorlds contains the IDs of the servers whose world is backed up or restored right now
+var busyWorlds = map[string]bool{}
+var busyWorldsMutex sync.Mutex
+
+// lockWorld Returns false if the world of the server is already backed up or restored
+func lockWorld(serverID string) bool {
+	busyWorldsMutex.Lock()
+	defer busyWorldsMutex.Unlock()
+	if busyWorlds[serverID] {
+		return false
+	}
+	busyWorlds[serverID] = true
+	return true
+}
+
+func unlockWorld(serverID string) {
+	busyWorldsMutex.Lock()
+	delete(busyWorlds, serverID)
+	busyWorldsMutex.Unlock()
+}
+
+// GetBackupDir Returns the directory containing the backups of the server
+func GetBackupDir(serverID string) string {
+	return filepath.Join(config.DataDir, config.McBackupsDir, serverID)
+}
+
+// GetBackupPath Returns the path of the archive of the backup
+func GetBackupPath(backup *models.DBBackup) string {
+	return filepath.Join(GetBackupDir(backup.ServerID), backup.FileName)
+}
+
+// GetBackup Returns ErrBackupNotFound if the server has no backup with the ID
+func GetBackup(serverID string, backupID uint) (models.DBBackup, error) {
+	backup, err := db.GetBackup(serverID, backupID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return backup, ErrBackupNotFound
+	}
+	return backup, err
+}
+
+// GetBackupByID Returns ErrBackupNotFound if no backup with the ID exists
+func GetBackupByID(backupID uint) (models.DBBackup, error) {
+	backup, err := db.GetBackupByID(backupID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return backup, ErrBackupNotFound
+	}
+	return backup, err
+}
+
+// CreateBackup Archives the world directory of the server and applies the retention policy of the server afterwards
+// A running mc server flushes the world to the disk first and doesn't save while the archive is created
+// Returns ErrWorldBusy if the world is already backed up or restored
+func CreateBackup(server *models.DBMcServerContainer, userID uint, scheduled bool) (models.DBBackup, error) {
+	if !lockWorld(server.ServerID) {
+		return models.DBBackup{}, ErrWorldBusy
+	}
+	defer unlockWorld(server.ServerID)
+
+	savingDisabled, err := disableWorldSaving(server)
+	if err != nil {
+		return models.DBBackup{}, fmt.Errorf("couldn't flush the world: %w", err)
+	}
+	if savingDisabled {
+		defer func() {
+			if _, err := ExecuteMcServerCommand(server, "save-on"); err != nil {
+				log.Error().Err(err).Msgf("Couldn't enable world saving of server %s again", server.ServerID)
+			}
+		}()
+	}
+
+	backupDir := GetBackupDir(server.ServerID)
+	if err := os.MkdirAll(backupDir, os.ModePerm); err != nil {
+		return models.DBBackup{}, err
+	}
+	fileName := time.Now().UTC().Format("20060102-150405.000") + backupFileExtension
+	sizeBytes, checksum, err := archiveWorld(GetMcWorldDir(server.Port), filepath.Join(backupDir, fileName))
+	if err != nil {
+		return models.DBBackup{}, err
+	}
+	backup := models.DBBackup{
+		ServerID:  server.ServerID,
+		FileName:  fileName,
+		McVersion: server.McVersion,
+		SizeBytes: sizeBytes,
+		SHA256:    checksum,
+		Scheduled: scheduled,
+		UserID:    userID,
+	}
+	if err := db.AddBackup(&backup); err != nil {
+		os.Remove(filepath.Join(backupDir, fileName))
+		return backup, err
+	}
+	log.Info().Msgf("Created backup %s of server %s (%d bytes)", fileName, server.ServerID, sizeBytes)
+
+	if err := ApplyBackupRetention(server.ServerID); err != nil {
+		log.Error().Err(err).Msgf("Couldn't apply backup retention of server %s", server.ServerID)
+	}
+	return backup, nil
+}
+
+// disableWorldSaving Runs save-off and save-all flush, so the world files don't change while they are archived
+// Returns false if the server isn't running, its world isn't written anyway
+func disableWorldSaving(server *models.DBMcServerContainer) (bool, error) {
+	if _, err := ExecuteMcServerCommand(server, "save-off"); err != nil {
+		if errors.Is(err, ErrServerNotRunning) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := ExecuteMcServerCommandUntil(server, "save-all flush", config.McSaveCompleteOutput); err != nil {
+		ExecuteMcServerCommand(server, "save-on")
+		return false, err
+	}
+	return true, nil
+}
+
+// archiveWorld Writes the world directory as gzip compressed tar archive to the target path
+// Returns the size and the SHA256 checksum of the archive. The archive is renamed into place once it's complete
+func archiveWorld(worldDir string, targetPath string) (int64, string, error) {
+	file, err := os.Create(targetPath + ".tmp")
+	if err != nil {
+		return 0, "", err
+	}
+	hash := sha256.New()
+	err = WriteWorldTarGz(io.MultiWriter(file, hash), worldDir)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(targetPath+".tmp", targetPath)
+	}
+	if err != nil {
+		os.Remove(targetPath + ".tmp")
+		return 0, "", err
+	}
+	info, err := os.Stat(targetPath)
+	if err != nil {
+		return 0, "", err
+	}
+	return info.Size(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteWorldTarGz Writes the regular files and directories of the world directory as gzip compressed tar archive
+// The paths in the archive are relative to the world directory. The session.lock of a running server is left out
+func WriteWorldTarGz(w io.Writer, worldDir string) error {
+	return writeTarGz(w, worldDir, "")
+}
+
+// writeTarGz Writes the files of the directory as gzip compressed tar archive, the paths in the archive start with the prefix
+func writeTarGz(w io.Writer, dir string, prefix string) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	err := walkWorldFiles(dir, prefix, func(name string, path string, info fs.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if err := tarWriter.WriteHeader(header); err != nil || info.IsDir() {
+			return err
+		}
+		return copyFileTo(tarWriter, path, header.Size)
+	})
+	if err != nil {
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// walkWorldFiles Calls fn for the regular files and directories of the world directory except the session.lock
+// name is the slash separated path relative to dir with the prefix, names of directories end with a slash
+func walkWorldFiles(dir string, prefix string, fn func(name string, path string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relativePath, err := filepath.Rel(dir, path)
+		if err != nil || relativePath == "." || entry.Name() == "session.lock" {
+			return err
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			// e.g. symlinks could point outside of the world
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		name := prefix + filepath.ToSlash(relativePath)
+		if entry.IsDir() {
+			name += "/"
+		}
+		return fn(name, path, info)
+	})
+}
+
+// copyFileTo Copies size bytes of the file to w, files which grew in the meantime are cut off
+func copyFileTo(w io.Writer, path string, size int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.CopyN(w, file, size)
+	return err
+}
+
+// DeleteBackup Removes the archive and the db entry of the backup
+func DeleteBackup(backup *models.DBBackup) error {
+	if err := os.Remove(GetBackupPath(backup)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return db.DeleteBackup(backup)
+}
+
+// GetBackupRetention Returns the retention policy of the backup schedule of the server or the default policy
+func GetBackupRetention(serverID string) models.BackupRetention {
+	if schedule, err := db.GetBackupSchedule(serverID); err == nil {
+		return schedule.BackupRetention
+	}
+	return models.BackupRetention{
+		KeepLast:   config.BackupDefaultKeepLast,
+		KeepDaily:  config.BackupDefaultKeepDaily,
+		KeepWeekly: config.BackupDefaultKeepWeekly,
+	}
+}
+
+// ApplyBackupRetention Deletes the backups of the server which aren't kept by its retention policy
+func ApplyBackupRetention(serverID string) error {
+	backups, err := db.GetBackups(serverID)
+	if err != nil {
+		return err
+	}
+	for _, backup := range selectExpiredBackups(backups, GetBackupRetention(serverID)) {
+		log.Info().Msgf("Deleting backup %s of server %s (retention)", backup.FileName, serverID)
+		if err := DeleteBackup(&backup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectExpiredBackups Returns the scheduled backups which aren't kept by any rule of the retention, the backups have to be sorted newest first
+// Manual backups are neither counted by the rules nor deleted
+func selectExpiredBackups(allBackups []models.DBBackup, retention models.BackupRetention) []models.DBBackup {
+	var backups []models.DBBackup
+	for _, backup := range allBackups {
+		if backup.Scheduled {
+			backups = append(backups, backup)
+		}
+	}
+	keep := map[uint]bool{}
+	for i := 0; i < len(backups) && i < retention.KeepLast; i++ {
+		keep[backups[i].ID] = true
+	}
+	keepNewestPerPeriod(backups, retention.KeepDaily, keep, func(t time.Time) string {
+		return t.Local().Format("2006-01-02")
+	})
+	keepNewestPerPeriod(backups, retention.KeepWeekly, keep, func(t time.Time) string {
+		year, week := t.Local().ISOWeek()
+		return fmt.Sprintf("%d-%d", year, week)
+	})
+
+	var expired []models.DBBackup
+	for _, backup := range backups {
+		if !keep[backup.ID] {
+			expired = append(expired, backup)
+		}
+	}
+	return expired
+}
+
+// keepNewestPerPeriod Keeps the newest backup of each of the last `count` periods with backups
+func keepNewestPerPeriod(backups []models.DBBackup, count int, keep map[uint]bool, period func(t time.Time) string) {
+	seenPeriods := map[string]bool{}
+	for _, backup := range backups {
+		if len(seenPeriods) >= count {
+			return
+		}
+		curPeriod := period(backup.CreatedAt)
+		if !seenPeriods[curPeriod] {
+			seenPeriods[curPeriod] = true
+			keep[backup.ID] = true
+		}
+	}
+}
+
+// SetBackupSchedule Validates and saves the backup schedule of the server, the retention is applied immediately
+// Returns an error wrapping ErrInvalidBackupSchedule if the cron expression or the retention isn't valid
+func SetBackupSchedule(serverID string, cron string, retention models.BackupRetention) (models.DBBackupSchedule, error) {
+	if _, err := utils.ParseCron(cron); err != nil {
+		return models.DBBackupSchedule{}, fmt.Errorf("%w: %s", ErrInvalidBackupSchedule, err)
+	}
+	for _, keep := range []int{retention.KeepLast, retention.KeepDaily, retention.KeepWeekly} {
+		if keep < 0 || keep > config.BackupMaximumKeep {
+			return models.DBBackupSchedule{}, fmt.Errorf("%w: the retention rules must be between 0 and %d", ErrInvalidBackupSchedule, config.BackupMaximumKeep)
+		}
+	}
+	if retention == (models.BackupRetention{}) {
+		return models.DBBackupSchedule{}, fmt.Errorf("%w: at least one retention rule must keep backups", ErrInvalidBackupSchedule)
+	}
+	schedule, err := db.SetBackupSchedule(serverID, cron, retention)
+	if err != nil {
+		return schedule, err
+	}
+	if err := ApplyBackupRetention(serverID); err != nil {
+		log.Error().Err(err).Msgf("Couldn't apply backup retention of server %s", serverID)
+	}
+	return schedule, nil
+}
+
+// StartBackupScheduler Creates the backups of the running servers whose backup schedule is due in the background
+func StartBackupScheduler() {
+	go func() {
+		ticker := time.NewTicker(config.BackupSchedulerInterval)
+		for now := range ticker.C {
+			runDueBackups(now.Truncate(time.Minute))
+		}
+	}()
+}
+
+func runDueBackups(now time.Time) {
+	schedules, err := db.GetBackupSchedules()
+	if err != nil {
+		log.Error().Err(err).Msg("Couldn't fetch backup schedules")
+		return
+	}
+	for _, schedule := range schedules {
+		cron, err := utils.ParseCron(schedule.Cron)
+		if err != nil || !cron.Matches(now) || (schedule.LastRunAt != nil && !schedule.LastRunAt.Before(now)) {
+			continue
+		}
+		if err := db.UpdateBackupScheduleLastRun(&schedule, now); err != nil {
+			log.Error().Err(err).Msgf("Couldn't update backup schedule of server %s", schedule.ServerID)
+			continue
+		}
+		server, err := db.GetMcServerData(schedule.ServerID)
+		if err != nil || server.Status != enums.Running {
+			// the world of a stopped server doesn't change
+			continue
+		}
+		go func(server models.DBMcServerContainer) {
+			if _, err := CreateBackup(&server, SystemUserID, true); err != nil {
+				log.Error().Err(err).Msgf("Scheduled backup of server %s failed", server.ServerID)
+			}
+		}(server)
+	}
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"github.com/instantmc/server/pkg/models"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSelectExpiredBackups(t *testing.T) {
+	now := time.Date(2023, 3, 8, 12, 0, 0, 0, time.Local)
+	var backups []models.DBBackup
+	// two backups per day of the last 21 days, newest first
+	for i := 0; i < 42; i++ {
+		createdAt := now.Add(-time.Duration(i) * 12 * time.Hour)
+		backups = append(backups, models.DBBackup{Model: gorm.Model{ID: uint(i + 1), CreatedAt: createdAt}, Scheduled: true})
+	}
+	// manual backups are kept and don't count for the rules
+	backups = append([]models.DBBackup{{Model: gorm.Model{ID: 100, CreatedAt: now.Add(time.Minute)}}}, backups...)
+
+	expired := selectExpiredBackups(backups, models.BackupRetention{KeepLast: 3, KeepDaily: 5, KeepWeekly: 3})
+	kept := map[uint]bool{}
+	for _, backup := range backups {
+		kept[backup.ID] = true
+	}
+	for _, backup := range expired {
+		delete(kept, backup.ID)
+	}
+	// the last 3 backups, the newest of 5 days (IDs 1, 3, 5, 7, 9) and the newest of 3 weeks
+	// 2023-03-08 is a wednesday, the previous weeks start with the backups of sunday (IDs 7 and 21)
+	for _, id := range []uint{100, 1, 2, 3, 5, 7, 9, 21} {
+		if !kept[id] {
+			t.Errorf("Backup %d should be kept", id)
+		}
+	}
+	if len(kept) != 8 {
+		t.Errorf("8 backups should be kept, got %v", kept)
+	}
+
+	if expired := selectExpiredBackups(backups[:3], models.BackupRetention{KeepLast: 5}); len(expired) != 0 {
+		t.Errorf("No backup should expire, got %d", len(expired))
+	}
+}
+
+func TestWriteWorldTarGz(t *testing.T) {
+	worldDir := t.TempDir()
+	os.MkdirAll(filepath.Join(worldDir, "world", "region"), os.ModePerm)
+	os.WriteFile(filepath.Join(worldDir, "world", "level.dat"), []byte("level"), 0644)
+	os.WriteFile(filepath.Join(worldDir, "world", "session.lock"), []byte("lock"), 0644)
+	os.Symlink("/etc/passwd", filepath.Join(worldDir, "passwd"))
+
+	var archive bytes.Buffer
+	if err := WriteWorldTarGz(&archive, worldDir); err != nil {
+		t.Fatal(err)
+	}
+	gzipReader, err := gzip.NewReader(&archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	names := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(tarReader)
+		names[header.Name] = string(content)
+	}
+	if names["world/level.dat"] != "level" {
+		t.Errorf("level.dat is missing, got %v", names)
+	}
+	for _, name := range []string{"world/", "world/region/"} {
+		if _, ok := names[name]; !ok {
+			t.Errorf("Directory %s is missing", name)
+		}
+	}
+	for _, name := range []string{"world/session.lock", "passwd"} {
+		if _, ok := names[name]; ok {
+			t.Errorf("%s shouldn't be archived", name)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/enums"
+	"strings"
+)
+
+var ErrCommandNotAllowed = errors.New("command not allowed")
+
+// CheckCommandAllowed Returns an error wrapping ErrCommandNotAllowed if the role must not run the command
+// The lists are defined by config.CommandAllowlist and config.CommandDenylist
+func CheckCommandAllowed(role enums.UserRole, command string) error {
+	normalized := normalizeCommand(command)
+	for _, executed := range executedCommands(normalized) {
+		for _, denied := range config.CommandDenylist[role] {
+			if commandMatches(executed, denied) {
+				return fmt.Errorf("%w: %s is denied for the role %s", ErrCommandNotAllowed, denied, role)
+			}
+		}
+	}
+	allowlist, restricted := config.CommandAllowlist[role]
+	if !restricted {
+		return nil
+	}
+	for _, allowed := range allowlist {
+		if commandMatches(normalized, allowed) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: the role %s may only run %s", ErrCommandNotAllowed, role, strings.Join(allowlist, ", "))
+}
+
+// normalizeCommand Removes the leading slash, the namespace of the command (e.g. `minecraft:`) and repeated whitespace
+// and converts the command to lower case
+func normalizeCommand(command string) string {
+	words := strings.Fields(strings.ToLower(strings.TrimPrefix(strings.TrimSpace(command), "/")))
+	if len(words) == 0 {
+		return ""
+	}
+	if _, name, found := strings.Cut(words[0], ":"); found {
+		words[0] = name
+	}
+	return strings.Join(words, " ")
+}
+
+// executedCommands Returns the normalized command and every command `execute` may run with it
+// Every word `run` of an `execute` command may start a command, arguments like a player named `run` can't hide one
+func executedCommands(normalizedCommand string) []string {
+	result := []string{normalizedCommand}
+	words := strings.Split(normalizedCommand, " ")
+	if words[0] != "execute" {
+		return result
+	}
+	for i, word := range words {
+		if word == "run" && i+1 < len(words) {
+			result = append(result, normalizeCommand(strings.Join(words[i+1:], " ")))
+		}
+	}
+	return result
+}
+
+// commandMatches Returns true if the command starts with all words of the entry
+// An entry ending with `$` has to match the whole command
+func commandMatches(normalizedCommand string, entry string) bool {
+	if exactEntry := strings.TrimSuffix(entry, "$"); exactEntry != entry {
+		return normalizedCommand == normalizeCommand(exactEntry)
+	}
+	entry = normalizeCommand(entry)
+	return normalizedCommand == entry || strings.HasPrefix(normalizedCommand, entry+" ")
+}
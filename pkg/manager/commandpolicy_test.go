@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"errors"
+	"github.com/instantmc/server/pkg/enums"
+	"testing"
+)
+
+func TestCheckCommandAllowed(t *testing.T) {
+	cases := []struct {
+		role    enums.UserRole
+		command string
+		allowed bool
+	}{
+		{enums.Viewer, "/list", true},
+		{enums.Viewer, "time  QUERY daytime", true},
+		{enums.Viewer, "/time set day", false},
+		{enums.Viewer, "/op Steve", false},
+		{enums.Operator, "/whitelist add Steve", true},
+		{enums.Operator, "/op Steve", false},
+		{enums.Operator, "/opinion", true},
+		{enums.Admin, "/op Steve", true},
+		{enums.Admin, " /STOP", false},
+		{enums.Operator, "minecraft:op Steve", false},
+		{enums.Operator, "/minecraft:deop x", false},
+		{enums.Admin, "minecraft:stop", false},
+		{enums.Admin, "bukkit:stop", false},
+		{enums.Operator, "execute run op Steve", false},
+		{enums.Operator, "execute as @a run stop", false},
+		{enums.Operator, "execute as run run op Steve", false},
+		{enums.Operator, "minecraft:execute if entity @a run execute run minecraft:deop Alex", false},
+		{enums.Admin, "execute as @a run stop", false},
+		{enums.Operator, "execute as @a run say hi", true},
+		{enums.Viewer, "execute run list", false},
+		{enums.Viewer, "difficulty", true},
+		{enums.Viewer, "/minecraft:difficulty", true},
+		{enums.Viewer, "difficulty hard", false},
+		{enums.Viewer, "minecraft:time set day", false},
+	}
+	for _, c := range cases {
+		err := CheckCommandAllowed(c.role, c.command)
+		if c.allowed && err != nil {
+			t.Errorf("%s should be allowed to run %q, got %s", c.role, c.command, err)
+		}
+		if !c.allowed && !errors.Is(err, ErrCommandNotAllowed) {
+			t.Errorf("%s shouldn't be allowed to run %q", c.role, c.command)
+		}
+	}
+}
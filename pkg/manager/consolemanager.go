@@ -0,0 +1,99 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/instantmc/server/pkg/api/mcserverapi"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"strings"
+	"sync"
+)
+
+var ErrServerNotRunning = errors.New("server is not running")
+
+var ErrInvalidCommand = errors.New("console commands must be a single line")
+
+// consoleMutexes serializes the commands per server, the outputs of concurrent commands would mix
+var consoleMutexes = map[string]*sync.Mutex{}
+var consoleMutexesMutex sync.Mutex
+
+// ExecuteMcServerCommand Writes the command to the console of the mc server and returns the console output following it
+// The mc server reads its console from the stdin of the container, the output are the log lines written afterwards
+// The output is complete once the console has been quiet for config.McCommandOutputIdleTime
+// Returns ErrServerNotRunning if the server has no running container and ErrInvalidCommand if the command has several lines
+func ExecuteMcServerCommand(server *models.DBMcServerContainer, command string) (string, error) {
+	return executeMcServerCommand(server, command, "")
+}
+
+// ExecuteMcServerCommandUntil Runs the command like ExecuteMcServerCommand, but waits until a line containing expectedOutput is logged
+// e.g. `save-all flush` logs config.McSaveCompleteOutput once the world is written
+func ExecuteMcServerCommandUntil(server *models.DBMcServerContainer, command string, expectedOutput string) (string, error) {
+	return executeMcServerCommand(server, command, expectedOutput)
+}
+
+func executeMcServerCommand(server *models.DBMcServerContainer, command string, expectedOutput string) (string, error) {
+	// a line break would start a second command which hasn't been checked against the command policy
+	if strings.ContainsAny(command, "\r\n") {
+		return "", ErrInvalidCommand
+	}
+	if server.Status != enums.Running || server.ContainerID == "" {
+		return "", ErrServerNotRunning
+	}
+	consoleMutex := getConsoleMutex(server.ServerID)
+	consoleMutex.Lock()
+	defer consoleMutex.Unlock()
+
+	inspect, err := GetContainerStats(server.ContainerID)
+	if err != nil {
+		return "", err
+	}
+	if !inspect.Config.OpenStdin {
+		return "", fmt.Errorf("container %s has been created without console, restart the server", server.ContainerID)
+	}
+
+	commandCtx, cancel := context.WithTimeout(ctx, config.McCommandTimeout)
+	defer cancel()
+	console, err := attachContainerConsole(commandCtx, server.ContainerID)
+	if err != nil {
+		return "", err
+	}
+	defer console.Close()
+
+	lines := make(chan string)
+	go func() {
+		scanLogLines(commandCtx, console.Reader, lines)
+		close(lines)
+	}()
+	output, err := mcserverapi.ExecuteCommand(console.Conn, lines, command, expectedOutput, config.McCommandOutputIdleTime, config.McCommandTimeout)
+	if errors.Is(err, mcserverapi.ErrConsoleClosed) {
+		err = ErrServerNotRunning
+	}
+	if err != nil {
+		return output, fmt.Errorf("%s: %w", command, err)
+	}
+	return output, nil
+}
+
+// attachContainerConsole Attaches to stdin and the multiplexed stdout and stderr of the container
+// Only new output is received, the connection has to be closed by the caller
+func attachContainerConsole(attachCtx context.Context, containerID string) (types.HijackedResponse, error) {
+	return cli.ContainerAttach(attachCtx, containerID, types.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  true,
+		Stdout: true,
+		Stderr: true,
+	})
+}
+
+func getConsoleMutex(serverID string) *sync.Mutex {
+	consoleMutexesMutex.Lock()
+	defer consoleMutexesMutex.Unlock()
+	if _, ok := consoleMutexes[serverID]; !ok {
+		consoleMutexes[serverID] = &sync.Mutex{}
+	}
+	return consoleMutexes[serverID]
+}
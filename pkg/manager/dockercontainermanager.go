@@ -102,6 +102,8 @@ func RunContainer(imageName string, containerName string, containerPort int, env
 			nat.Port(port): {},
 		},
 		Env: env,
+		// the mc server reads its console commands from stdin, see attachContainerConsole
+		OpenStdin: true,
 	}, &container.HostConfig{
 		PortBindings: nat.PortMap{
 			nat.Port(port): []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: strconv.Itoa(containerPort)}},
@@ -123,6 +125,8 @@ func RunContainer(imageName string, containerName string, containerPort int, env
 	}
 
 	if err := cli.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		// the created container would block its name for the next attempt
+		cli.ContainerRemove(ctx, resp.ID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
 		return "", err
 	}
 
@@ -178,6 +182,21 @@ func GetContainerRamSizeEnv(containerID string) (int, error) {
 	return config.DefaultRamSize, nil
 }
 
+// GetContainerWorldProfileEnv Returns the name of the world profile the world of the container has been generated with
+// Returns an empty string for the default world
+func GetContainerWorldProfileEnv(containerID string) (string, error) {
+	stats, err := GetContainerStats(containerID)
+	if err != nil {
+		return "", err
+	}
+	for _, curEnv := range stats.Config.Env {
+		if strings.HasPrefix(curEnv, worldProfileEnvKey+"=") {
+			return strings.TrimPrefix(curEnv, worldProfileEnvKey+"="), nil
+		}
+	}
+	return "", nil
+}
+
 func IsContainerPaused(containerID string) (bool, error) {
 	containerStats, err := GetContainerStats(containerID)
 	if err != nil {
@@ -186,7 +205,21 @@ func IsContainerPaused(containerID string) (bool, error) {
 	return containerStats.State.Paused, nil
 }
 
+// StopContainer Gracefully stops the container. The container is killed if it doesn't stop within config.McServerStopTimeout seconds
+func StopContainer(containerID string) error {
+	timeout := config.McServerStopTimeout
+	return cli.ContainerStop(ctx, containerID, container.StopOptions{Timeout: &timeout})
+}
+
+// KillContainer Removes the container immediately. The log of a mc server container (not of prepared ones) is archived before
 func KillContainer(containerID string) error {
+	if containerJSON, err := GetContainerStats(containerID); err == nil && !strings.HasPrefix(containerJSON.Name, "/"+config.WaitingReadyContainerName) {
+		if serverID := strings.TrimPrefix(containerJSON.Name, "/"+config.ContainerBaseName); serverID != containerJSON.Name {
+			if err := ArchiveContainerLogs(serverID, containerID); err != nil {
+				log.Warn().Err(err).Msgf("Couldn't archive log of container %s", containerID)
+			}
+		}
+	}
 	return cli.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
 }
 
@@ -253,6 +286,10 @@ func SubscribeToContainerStats(containerID string, jsonStats *chan string) error
 	return nil
 }
 
+func GetDockerInfo() (types.Info, error) {
+	return cli.Info(ctx)
+}
+
 func Close() {
 	cli.Close()
 }
@@ -1,11 +1,27 @@
 package manager
 
 import (
+	"errors"
+	"fmt"
 	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/models"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidFilePath = errors.New("invalid file path")
+	ErrFileNotFound    = errors.New("file not found")
+	ErrFileExists      = errors.New("file already exists")
+	ErrFileTooLarge    = errors.New("file too large")
+	ErrIsDirectory     = errors.New("the path is a directory")
+	ErrNotDirectory    = errors.New("the path is no directory")
 )
 
 // EnsureDirsExist Checks if all needed directories exist. If not they will be created
@@ -21,14 +37,309 @@ func EnsureDirsExist() {
 	}
 }
 
+// GetMcWorldDir Returns the directory which is mounted as world into the container of the server with the port
+func GetMcWorldDir(port int) string {
+	return filepath.Join(config.DataDir, config.McWorldsDir, strconv.Itoa(port))
+}
+
 func CreateMcWorld(port int) error {
-	path := filepath.Join(config.DataDir, config.McWorldsDir, strconv.Itoa(port))
+	path := GetMcWorldDir(port)
 	log.Info().Msgf("Creating mc world %s...", path)
 	return os.MkdirAll(path, os.ModePerm)
 }
 
 func DeleteMcWorld(port int) error {
-	path := filepath.Join(config.DataDir, config.McWorldsDir, strconv.Itoa(port))
+	path := GetMcWorldDir(port)
 	log.Info().Msgf("Deleting mc world %s...", path)
 	return os.RemoveAll(path)
 }
+
+// resolveServerPath Returns the absolute path of the slash separated path inside the world directory of the server with the port
+// and the cleaned path relative to the world directory, which is empty for the world directory itself
+// Returns an error wrapping ErrInvalidFilePath if the path contains `..` or leads outside of the world directory via a symlink
+func resolveServerPath(port int, filePath string) (string, string, error) {
+	if strings.ContainsRune(filePath, 0) {
+		return "", "", fmt.Errorf("%w: %q", ErrInvalidFilePath, filePath)
+	}
+	slashPath := strings.ReplaceAll(filePath, "\\", "/")
+	if slices.Contains(strings.Split(slashPath, "/"), "..") {
+		return "", "", fmt.Errorf("%w: %s leaves the server directory", ErrInvalidFilePath, filePath)
+	}
+	relativePath := strings.TrimPrefix(path.Clean("/"+slashPath), "/")
+
+	realRoot, err := getRealServerDir(port)
+	if err != nil {
+		return "", "", err
+	}
+	absolutePath := filepath.Join(realRoot, filepath.FromSlash(relativePath))
+	if err := checkServerPath(port, absolutePath); err != nil {
+		return "", "", err
+	}
+	return absolutePath, relativePath, nil
+}
+
+// getRealServerDir Returns the absolute world directory of the server with the port with all symlinks resolved
+func getRealServerDir(port int) (string, error) {
+	root, err := filepath.Abs(GetMcWorldDir(port))
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrFileNotFound
+	}
+	return realRoot, err
+}
+
+// checkServerPath Returns an error wrapping ErrInvalidFilePath if the deepest existing parent of the absolute path leads
+// outside of the world directory of the server via a symlink. Symlinks can be created by the mc server or a plugin at any time,
+// so the check is repeated right before a file is modified
+func checkServerPath(port int, absolutePath string) error {
+	realRoot, err := getRealServerDir(port)
+	if err != nil {
+		return err
+	}
+	existingPath := absolutePath
+	for {
+		realPath, err := filepath.EvalSymlinks(existingPath)
+		if err == nil {
+			if !isInsideDir(realPath, realRoot) {
+				return fmt.Errorf("%w: %s leaves the server directory", ErrInvalidFilePath, filepath.Base(absolutePath))
+			}
+			return nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		existingPath = filepath.Dir(existingPath)
+	}
+}
+
+// checkOpenedServerFile Returns an error wrapping ErrInvalidFilePath unless the opened file is the file which the path resolves to
+// inside of the world directory, e.g. if a directory of the path has been replaced with a symlink after the path has been checked
+func checkOpenedServerFile(port int, file *os.File, absolutePath string) error {
+	openedInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	realRoot, err := getRealServerDir(port)
+	if err != nil {
+		return err
+	}
+	realPath, err := filepath.EvalSymlinks(absolutePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil && isInsideDir(realPath, realRoot) {
+		if info, err := os.Stat(realPath); err == nil && os.SameFile(openedInfo, info) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s has been replaced while it was opened", ErrInvalidFilePath, filepath.Base(absolutePath))
+}
+
+// isInsideDir Returns true if the path is the directory or inside of it, both paths have to be absolute and without symlinks
+func isInsideDir(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// ListServerFiles Lists the directory in the world directory of the server, directories first
+func ListServerFiles(port int, dirPath string) ([]models.ServerFile, error) {
+	absolutePath, relativePath, err := resolveServerPath(port, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(absolutePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	} else if err != nil {
+		if info, statErr := os.Stat(absolutePath); statErr == nil && !info.IsDir() {
+			return nil, ErrNotDirectory
+		}
+		return nil, err
+	}
+	if len(entries) > config.ServerFileListMaximumEntries {
+		return nil, fmt.Errorf("%w: the directory has more than %d entries", ErrFileTooLarge, config.ServerFileListMaximumEntries)
+	}
+
+	files := []models.ServerFile{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// e.g. removed in the meantime
+			continue
+		}
+		files = append(files, models.ServerFile{
+			Name:       entry.Name(),
+			Path:       path.Join(relativePath, entry.Name()),
+			IsDir:      entry.IsDir(),
+			SizeBytes:  info.Size(),
+			ModifiedAt: info.ModTime(),
+		})
+	}
+	slices.SortFunc(files, func(a, b models.ServerFile) bool {
+		if a.IsDir != b.IsDir {
+			return a.IsDir
+		}
+		return a.Name < b.Name
+	})
+	return files, nil
+}
+
+// OpenServerFile Opens a file in the world directory of the server for reading
+// Returns an error wrapping ErrFileTooLarge if it exceeds config.ServerFileMaximumBytes
+func OpenServerFile(port int, filePath string) (*os.File, os.FileInfo, error) {
+	absolutePath, _, err := resolveServerPath(port, filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := os.Stat(absolutePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrFileNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return nil, nil, ErrIsDirectory
+	}
+	if info.Size() > config.ServerFileMaximumBytes {
+		return nil, nil, fmt.Errorf("%w: at most %d bytes can be read", ErrFileTooLarge, config.ServerFileMaximumBytes)
+	}
+	file, err := os.Open(absolutePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkOpenedServerFile(port, file, absolutePath); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// WriteServerFile Creates or replaces a file in the world directory of the server, its directory has to exist
+// The content is written to a temporary file first, so the mc server never reads a partial file
+// Returns an error wrapping ErrFileTooLarge if the content exceeds config.ServerFileMaximumBytes
+func WriteServerFile(port int, filePath string, content io.Reader) error {
+	absolutePath, relativePath, err := resolveServerPath(port, filePath)
+	if err != nil {
+		return err
+	}
+	if relativePath == "" {
+		return ErrIsDirectory
+	}
+	if info, err := os.Stat(absolutePath); err == nil && info.IsDir() {
+		return ErrIsDirectory
+	}
+	if info, err := os.Stat(filepath.Dir(absolutePath)); errors.Is(err, os.ErrNotExist) {
+		return ErrFileNotFound
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return ErrNotDirectory
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(absolutePath), "."+filepath.Base(absolutePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if err := checkOpenedServerFile(port, tmpFile, tmpFile.Name()); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	written, err := io.Copy(tmpFile, io.LimitReader(content, config.ServerFileMaximumBytes+1))
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written > config.ServerFileMaximumBytes {
+		return fmt.Errorf("%w: at most %d bytes can be written", ErrFileTooLarge, config.ServerFileMaximumBytes)
+	}
+	if err := os.Chmod(tmpFile.Name(), 0644); err != nil {
+		return err
+	}
+	// the rename replaces a symlink at the path instead of following it
+	if err := checkServerPath(port, tmpFile.Name()); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), absolutePath)
+}
+
+// MakeServerDir Creates a directory including its parents in the world directory of the server
+func MakeServerDir(port int, dirPath string) error {
+	absolutePath, _, err := resolveServerPath(port, dirPath)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(absolutePath); err == nil && !info.IsDir() {
+		return ErrFileExists
+	}
+	if err := checkServerPath(port, absolutePath); err != nil {
+		return err
+	}
+	return os.MkdirAll(absolutePath, os.ModePerm)
+}
+
+// MoveServerFile Moves or renames a file or directory in the world directory of the server
+// Returns ErrFileExists if the target exists, nothing is overwritten
+func MoveServerFile(port int, fromPath string, toPath string) error {
+	absoluteFrom, relativeFrom, err := resolveServerPath(port, fromPath)
+	if err != nil {
+		return err
+	}
+	absoluteTo, relativeTo, err := resolveServerPath(port, toPath)
+	if err != nil {
+		return err
+	}
+	if relativeFrom == "" || relativeTo == "" {
+		return fmt.Errorf("%w: the server directory itself can't be moved", ErrInvalidFilePath)
+	}
+	if relativeTo == relativeFrom || strings.HasPrefix(relativeTo, relativeFrom+"/") {
+		return fmt.Errorf("%w: a directory can't be moved into itself", ErrInvalidFilePath)
+	}
+	if _, err := os.Lstat(absoluteFrom); errors.Is(err, os.ErrNotExist) {
+		return ErrFileNotFound
+	} else if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(absoluteTo); err == nil {
+		return ErrFileExists
+	}
+	if info, err := os.Stat(filepath.Dir(absoluteTo)); errors.Is(err, os.ErrNotExist) {
+		return ErrFileNotFound
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return ErrNotDirectory
+	}
+	// the rename doesn't follow symlinks at the paths, only their directories are checked again
+	if err := checkServerPath(port, filepath.Dir(absoluteFrom)); err != nil {
+		return err
+	}
+	if err := checkServerPath(port, filepath.Dir(absoluteTo)); err != nil {
+		return err
+	}
+	return os.Rename(absoluteFrom, absoluteTo)
+}
+
+// DeleteServerFile Deletes a file or a directory including its content in the world directory of the server
+func DeleteServerFile(port int, filePath string) error {
+	absolutePath, relativePath, err := resolveServerPath(port, filePath)
+	if err != nil {
+		return err
+	}
+	if relativePath == "" {
+		return fmt.Errorf("%w: the server directory itself can't be deleted", ErrInvalidFilePath)
+	}
+	if _, err := os.Lstat(absolutePath); errors.Is(err, os.ErrNotExist) {
+		return ErrFileNotFound
+	} else if err != nil {
+		return err
+	}
+	// a symlink at the path itself is removed without following it, only its directory is checked again
+	if err := checkServerPath(port, filepath.Dir(absolutePath)); err != nil {
+		return err
+	}
+	return os.RemoveAll(absolutePath)
+}
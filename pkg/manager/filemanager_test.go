@@ -0,0 +1,151 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTempDataDir Runs the test in a temporary working directory, so the relative data directory is empty
+func useTempDataDir(t *testing.T) {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(workingDir) })
+}
+
+func TestResolveServerPath(t *testing.T) {
+	useTempDataDir(t)
+	const port = 25001
+	if err := CreateMcWorld(port); err != nil {
+		t.Fatal(err)
+	}
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(GetMcWorldDir(port), "escape"))
+
+	for _, filePath := range []string{"../25002/server.properties", "world/../../x", "..\\x", "escape", "escape/new.txt"} {
+		if _, _, err := resolveServerPath(port, filePath); !errors.Is(err, ErrInvalidFilePath) {
+			t.Errorf("%s: expected ErrInvalidFilePath, got %v", filePath, err)
+		}
+	}
+	for filePath, expected := range map[string]string{"": "", "/": "", "/world//level.dat": "world/level.dat", "./server.properties": "server.properties"} {
+		_, relativePath, err := resolveServerPath(port, filePath)
+		if err != nil || relativePath != expected {
+			t.Errorf("%q: expected %q, got %q %v", filePath, expected, relativePath, err)
+		}
+	}
+}
+
+func TestCheckOpenedServerFile(t *testing.T) {
+	useTempDataDir(t)
+	const port = 25001
+	if err := CreateMcWorld(port); err != nil {
+		t.Fatal(err)
+	}
+	if err := MakeServerDir(port, "plugins"); err != nil {
+		t.Fatal(err)
+	}
+	WriteServerFile(port, "plugins/config.yml", strings.NewReader("inside"))
+	file, _, err := OpenServerFile(port, "plugins/config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	absolutePath, _, err := resolveServerPath(port, "plugins/config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkOpenedServerFile(port, file, absolutePath); err != nil {
+		t.Errorf("The opened file should be accepted, got %v", err)
+	}
+
+	// the directory is replaced with a symlink after the path has been checked
+	outside := t.TempDir()
+	os.WriteFile(filepath.Join(outside, "config.yml"), []byte("outside"), 0644)
+	pluginsDir := filepath.Join(GetMcWorldDir(port), "plugins")
+	if err := os.Rename(pluginsDir, pluginsDir+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, pluginsDir); err != nil {
+		t.Fatal(err)
+	}
+	outsideFile, err := os.Open(absolutePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outsideFile.Close()
+	if err := checkOpenedServerFile(port, outsideFile, absolutePath); !errors.Is(err, ErrInvalidFilePath) {
+		t.Errorf("A file opened through a replaced directory should fail with ErrInvalidFilePath, got %v", err)
+	}
+	if err := checkServerPath(port, absolutePath); !errors.Is(err, ErrInvalidFilePath) {
+		t.Errorf("A path through a replaced directory should fail with ErrInvalidFilePath, got %v", err)
+	}
+}
+
+func TestServerFiles(t *testing.T) {
+	useTempDataDir(t)
+	const port = 25001
+	if err := CreateMcWorld(port); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := MakeServerDir(port, "datapacks/test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteServerFile(port, "server.properties", strings.NewReader("pvp=false")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteServerFile(port, "missing/file.txt", strings.NewReader("")); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Writing into a missing directory should fail with ErrFileNotFound, got %v", err)
+	}
+	if err := WriteServerFile(port, "datapacks", strings.NewReader("")); !errors.Is(err, ErrIsDirectory) {
+		t.Errorf("Writing a directory should fail with ErrIsDirectory, got %v", err)
+	}
+
+	files, err := ListServerFiles(port, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "datapacks" || !files[0].IsDir || files[1].Path != "server.properties" || files[1].SizeBytes != 9 {
+		t.Errorf("Expected datapacks and server.properties, got %+v", files)
+	}
+
+	if err := MoveServerFile(port, "server.properties", "datapacks/test/server.properties"); err != nil {
+		t.Fatal(err)
+	}
+	if err := MoveServerFile(port, "datapacks", "datapacks/test/inside"); !errors.Is(err, ErrInvalidFilePath) {
+		t.Errorf("Moving a directory into itself should fail with ErrInvalidFilePath, got %v", err)
+	}
+	WriteServerFile(port, "other", strings.NewReader("other"))
+	if err := MoveServerFile(port, "other", "datapacks/test/server.properties"); !errors.Is(err, ErrFileExists) {
+		t.Errorf("Moving onto an existing file should fail with ErrFileExists, got %v", err)
+	}
+
+	file, _, err := OpenServerFile(port, "datapacks/test/server.properties")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var content bytes.Buffer
+	content.ReadFrom(file)
+	file.Close()
+	if content.String() != "pvp=false" {
+		t.Errorf("Expected the moved content, got %q", content.String())
+	}
+
+	if err := DeleteServerFile(port, ""); !errors.Is(err, ErrInvalidFilePath) {
+		t.Errorf("Deleting the server directory should fail with ErrInvalidFilePath, got %v", err)
+	}
+	if err := DeleteServerFile(port, "datapacks"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := OpenServerFile(port, "datapacks/test/server.properties"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("Expected ErrFileNotFound after the deletion, got %v", err)
+	}
+}
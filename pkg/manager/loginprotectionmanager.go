@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// loginFailureMutex makes checking and counting login attempts atomic, so concurrent logins can't bypass the lockout
+var loginFailureMutex sync.Mutex
+
+type loginFailureSubject struct {
+	kind    enums.LoginFailureKind
+	subject string
+}
+
+// pendingLoginAttempts counts the logins per subject whose password is being verified right now
+var pendingLoginAttempts = map[loginFailureSubject]int{}
+
+// ReserveLoginAttempt Returns how long logins of the username or from the client ip are still refused
+// A duration of 0 means that the login may be attempted, FinishLoginAttempt has to be called once the password is verified
+// Attempts which are still pending count as failed, so parallel guesses can't exceed config.LoginFreeAttempts
+func ReserveLoginAttempt(username string, clientIP string) (time.Duration, error) {
+	loginFailureMutex.Lock()
+	defer loginFailureMutex.Unlock()
+
+	now := time.Now()
+	var lockout time.Duration
+	for kind, subject := range loginFailureSubjects(username, clientIP) {
+		failure, err := db.GetLoginFailure(kind, subject)
+		if err != nil {
+			return 0, err
+		}
+		if subjectLockout := loginAttemptLockout(failure, pendingLoginAttempts[loginFailureSubject{kind, subject}], now); subjectLockout > lockout {
+			lockout = subjectLockout
+		}
+	}
+	if lockout > 0 {
+		return lockout, nil
+	}
+	for kind, subject := range loginFailureSubjects(username, clientIP) {
+		pendingLoginAttempts[loginFailureSubject{kind, subject}]++
+	}
+	return 0, nil
+}
+
+// FinishLoginAttempt Ends an attempt reserved by ReserveLoginAttempt and counts it for the username and the client ip if it failed
+// Exceeding config.LoginFreeAttempts locks the subject out for an exponentially growing time span
+func FinishLoginAttempt(username string, clientIP string, failed bool) error {
+	loginFailureMutex.Lock()
+	defer loginFailureMutex.Unlock()
+
+	for kind, subject := range loginFailureSubjects(username, clientIP) {
+		key := loginFailureSubject{kind, subject}
+		if pendingLoginAttempts[key] <= 1 {
+			delete(pendingLoginAttempts, key)
+		} else {
+			pendingLoginAttempts[key]--
+		}
+	}
+	if !failed {
+		return nil
+	}
+
+	now := time.Now()
+	for kind, subject := range loginFailureSubjects(username, clientIP) {
+		failure, err := db.GetLoginFailure(kind, subject)
+		if err != nil {
+			return err
+		}
+		if failure.IsStale(now) {
+			failure.Failures = 0
+		}
+		failure.Failures++
+		failure.LastFailureAt = now
+		if lockout := calculateLoginLockout(failure.Failures); lockout > 0 {
+			failure.LockedUntil = now.Add(lockout)
+			log.Warn().Msgf("Locked out %s %s for %s after %d failed logins", kind, subject, lockout, failure.Failures)
+		}
+		if err := db.SaveLoginFailure(&failure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResetFailedLogins Forgets the failed logins of the username after a successful login
+// The failed logins of the client ip are kept, otherwise an attacker could reset them with an own account
+func ResetFailedLogins(username string) error {
+	return db.DeleteLoginFailuresOf(enums.LoginFailureUsername, username)
+}
+
+// StartLoginFailureSweeper Purges stale failed logins from the db in the background every config.SessionSweepInterval
+func StartLoginFailureSweeper() {
+	go func() {
+		for {
+			purged, err := db.DeleteStaleLoginFailures()
+			if err != nil {
+				log.Error().Err(err).Msg("Couldn't purge stale failed logins")
+			} else if purged > 0 {
+				log.Info().Msgf("Purged %d stale failed logins", purged)
+			}
+			time.Sleep(config.SessionSweepInterval)
+		}
+	}()
+}
+
+func loginFailureSubjects(username string, clientIP string) map[enums.LoginFailureKind]string {
+	return map[enums.LoginFailureKind]string{
+		enums.LoginFailureUsername: username,
+		enums.LoginFailureIP:       clientIP,
+	}
+}
+
+// loginAttemptLockout Returns how long a new login attempt of the subject has to wait
+// The pending attempts are expected to fail, a new attempt must wait if their failures would lock the subject out
+func loginAttemptLockout(failure models.LoginFailure, pending int, now time.Time) time.Duration {
+	if failure.IsLocked(now) {
+		return failure.LockedUntil.Sub(now)
+	}
+	if pending == 0 {
+		return 0
+	}
+	failures := failure.Failures
+	if failure.IsStale(now) {
+		failures = 0
+	}
+	return calculateLoginLockout(failures + pending)
+}
+
+// calculateLoginLockout Returns the lockout after the given number of consecutive failed logins
+func calculateLoginLockout(failures int) time.Duration {
+	exceeding := failures - config.LoginFreeAttempts
+	if exceeding <= 0 {
+		return 0
+	}
+	lockout := config.LoginBaseLockout
+	for i := 1; i < exceeding; i++ {
+		lockout *= 2
+		if lockout >= config.LoginMaximumLockout {
+			return config.LoginMaximumLockout
+		}
+	}
+	return lockout
+}
@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/models"
+	"testing"
+	"time"
+)
+
+func TestCalculateLoginLockout(t *testing.T) {
+	if lockout := calculateLoginLockout(config.LoginFreeAttempts); lockout != 0 {
+		t.Errorf("Failed logins within the free attempts shouldn't lock out, got %s", lockout)
+	}
+	if lockout := calculateLoginLockout(config.LoginFreeAttempts + 1); lockout != config.LoginBaseLockout {
+		t.Errorf("First exceeding failed login should lock out for %s, got %s", config.LoginBaseLockout, lockout)
+	}
+	if lockout := calculateLoginLockout(config.LoginFreeAttempts + 3); lockout != 4*config.LoginBaseLockout {
+		t.Errorf("Lockout should double with every failed login, got %s", lockout)
+	}
+	if lockout := calculateLoginLockout(config.LoginFreeAttempts + 1000); lockout != config.LoginMaximumLockout {
+		t.Errorf("Lockout should be limited to %s, got %s", config.LoginMaximumLockout, lockout)
+	}
+}
+
+func TestLoginAttemptLockout(t *testing.T) {
+	now := time.Now()
+	failure := models.LoginFailure{Failures: config.LoginFreeAttempts - 2, LastFailureAt: now}
+	if lockout := loginAttemptLockout(failure, 1, now); lockout != 0 {
+		t.Errorf("An attempt within the free attempts shouldn't wait, got %s", lockout)
+	}
+	if lockout := loginAttemptLockout(failure, 3, now); lockout != config.LoginBaseLockout {
+		t.Errorf("Pending attempts exceeding the free attempts should lock out, got %s", lockout)
+	}
+
+	// the first attempt after a lockout may be made, but no parallel one
+	failure = models.LoginFailure{Failures: config.LoginFreeAttempts + 1, LastFailureAt: now, LockedUntil: now.Add(-time.Second)}
+	if lockout := loginAttemptLockout(failure, 0, now); lockout != 0 {
+		t.Errorf("An attempt after the lockout shouldn't wait, got %s", lockout)
+	}
+	if lockout := loginAttemptLockout(failure, 1, now); lockout == 0 {
+		t.Errorf("A parallel attempt after the lockout should wait")
+	}
+	failure.LockedUntil = now.Add(time.Minute)
+	if lockout := loginAttemptLockout(failure, 0, now); lockout != time.Minute {
+		t.Errorf("A locked subject should wait until the end of the lockout, got %s", lockout)
+	}
+}
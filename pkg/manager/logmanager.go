@@ -0,0 +1,241 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/models"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maximumLogLineLength limits the length of a single log line, longer lines are truncated
+const maximumLogLineLength = 1024 * 1024
+
+// FollowContainerLogs Sends the last `tail` log lines of the container and every new line to `lines`
+// It returns as soon as the container stops or streamCtx is canceled
+func FollowContainerLogs(streamCtx context.Context, containerID string, tail int, lines chan<- string) error {
+	reader, err := cli.ContainerLogs(streamCtx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return scanLogLines(streamCtx, reader, lines)
+}
+
+// scanLogLines Splits the multiplexed stdout and stderr stream of a container without tty into lines
+func scanLogLines(streamCtx context.Context, reader io.Reader, lines chan<- string) error {
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeReader.Close()
+	go func() {
+		_, err := stdcopy.StdCopy(pipeWriter, pipeWriter, reader)
+		pipeWriter.CloseWithError(err)
+	}()
+
+	err := readLines(pipeReader, func(line string) bool {
+		select {
+		case lines <- line:
+			return true
+		case <-streamCtx.Done():
+			return false
+		}
+	})
+	if err == nil {
+		err = streamCtx.Err()
+	}
+	return err
+}
+
+// readLines Calls handle for every line of the reader until it returns false
+// Lines exceeding maximumLogLineLength are truncated instead of failing the whole log
+func readLines(r io.Reader, handle func(line string) bool) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	var line []byte
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			if len(line) > 0 {
+				handle(string(line))
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if remaining := maximumLogLineLength - len(line); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+		line = append(line, chunk...)
+		if isPrefix {
+			continue
+		}
+		if !handle(string(line)) {
+			return nil
+		}
+		line = line[:0]
+	}
+}
+
+// ArchiveContainerLogs Saves the complete log of the container with timestamps in the log directory of the server
+// The archives of a server are named by the time of archiving, so their names sort chronologically
+func ArchiveContainerLogs(serverID string, containerID string) error {
+	reader, err := cli.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Timestamps: true,
+	})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	archiveDir := getLogArchiveDir(serverID)
+	if err := os.MkdirAll(archiveDir, os.ModePerm); err != nil {
+		return err
+	}
+	shortContainerID := containerID
+	if len(shortContainerID) > 12 {
+		shortContainerID = shortContainerID[:12]
+	}
+	fileName := fmt.Sprintf("%s-%s.log", time.Now().UTC().Format("20060102-150405"), shortContainerID)
+	file, err := os.Create(filepath.Join(archiveDir, fileName))
+	if err != nil {
+		return err
+	}
+	_, err = stdcopy.StdCopy(file, file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return pruneLogArchives(serverID)
+}
+
+// pruneLogArchives Removes the oldest log archives of the server exceeding config.LogArchivesPerServer
+func pruneLogArchives(serverID string) error {
+	archives, err := os.ReadDir(getLogArchiveDir(serverID))
+	if err != nil {
+		return err
+	}
+	// the entries are sorted by name, which is the time of archiving
+	for i := 0; i < len(archives)-config.LogArchivesPerServer; i++ {
+		if err := os.Remove(filepath.Join(getLogArchiveDir(serverID), archives[i].Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteArchivedLogs Removes all archived logs of the server, e.g. when it's purged
+func DeleteArchivedLogs(serverID string) error {
+	return os.RemoveAll(getLogArchiveDir(serverID))
+}
+
+// HasArchivedLogs Returns true if logs of the server have been archived, e.g. of a deleted server
+func HasArchivedLogs(serverID string) bool {
+	entries, err := os.ReadDir(getLogArchiveDir(serverID))
+	return err == nil && len(entries) > 0
+}
+
+// GetMcServerLogs Returns the archived log lines of the server followed by the lines of its current container
+// An empty containerID only searches the archives
+func GetMcServerLogs(serverID string, containerID string, query models.LogQuery) ([]models.LogLine, error) {
+	var result []models.LogLine
+	collect := func(line models.LogLine) {
+		if !query.Matches(line) {
+			return
+		}
+		result = append(result, line)
+		// only the last lines are kept in memory
+		if query.Tail > 0 && len(result) >= 2*query.Tail {
+			result = append([]models.LogLine{}, result[len(result)-query.Tail:]...)
+		}
+	}
+
+	archives, err := os.ReadDir(getLogArchiveDir(serverID))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, archive := range archives {
+		if err := readArchivedLogs(filepath.Join(getLogArchiveDir(serverID), archive.Name()), collect); err != nil {
+			return nil, err
+		}
+	}
+
+	if containerID != "" {
+		options := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Timestamps: true}
+		if !query.Since.IsZero() {
+			options.Since = strconv.FormatInt(query.Since.Unix(), 10)
+		}
+		if !query.Until.IsZero() {
+			// docker compares with second precision, the exact filtering is done by collect
+			options.Until = strconv.FormatInt(query.Until.Unix()+1, 10)
+		}
+		if query.Grep == nil && query.Tail > 0 {
+			options.Tail = strconv.Itoa(query.Tail)
+		}
+		reader, err := cli.ContainerLogs(ctx, containerID, options)
+		if err != nil && !client.IsErrNotFound(err) {
+			return nil, err
+		}
+		if err == nil {
+			defer reader.Close()
+			lines := make(chan string)
+			var scanErr error
+			go func() {
+				scanErr = scanLogLines(ctx, reader, lines)
+				close(lines)
+			}()
+			for line := range lines {
+				collect(parseLogLine(line))
+			}
+			if scanErr != nil {
+				return nil, scanErr
+			}
+		}
+	}
+
+	if query.Tail > 0 && len(result) > query.Tail {
+		result = result[len(result)-query.Tail:]
+	}
+	return result, nil
+}
+
+func readArchivedLogs(path string, collect func(line models.LogLine)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return readLines(file, func(line string) bool {
+		collect(parseLogLine(line))
+		return true
+	})
+}
+
+// parseLogLine Splits a log line of docker into its timestamp and its content
+// Lines without timestamp are returned with the zero time
+func parseLogLine(raw string) models.LogLine {
+	timestamp, line, found := strings.Cut(raw, " ")
+	if parsedTime, err := time.Parse(time.RFC3339Nano, timestamp); found && err == nil {
+		return models.LogLine{Time: parsedTime, Line: line}
+	}
+	return models.LogLine{Line: raw}
+}
+
+func getLogArchiveDir(serverID string) string {
+	return filepath.Join(config.DataDir, config.McLogsDir, serverID)
+}
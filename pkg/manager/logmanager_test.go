@@ -0,0 +1,80 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/instantmc/server/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogLine(t *testing.T) {
+	line := parseLogLine("2023-03-02T09:12:40.123456789Z [Server thread/INFO]: Done (3.2s)!")
+	if line.Line != "[Server thread/INFO]: Done (3.2s)!" {
+		t.Errorf("Unexpected line content %q", line.Line)
+	}
+	if !line.Time.Equal(time.Date(2023, 3, 2, 9, 12, 40, 123456789, time.UTC)) {
+		t.Errorf("Unexpected line time %s", line.Time)
+	}
+	if line := parseLogLine("no timestamp"); line.Line != "no timestamp" || !line.Time.IsZero() {
+		t.Errorf("Lines without timestamp should be kept as they are, got %+v", line)
+	}
+}
+
+func TestScanLogLines(t *testing.T) {
+	var multiplexed bytes.Buffer
+	stdcopy.NewStdWriter(&multiplexed, stdcopy.Stdout).Write([]byte("first\nsec"))
+	stdcopy.NewStdWriter(&multiplexed, stdcopy.Stderr).Write([]byte("ond\nthird\n"))
+
+	lines := make(chan string)
+	scanErr := make(chan error, 1)
+	go func() {
+		scanErr <- scanLogLines(context.Background(), &multiplexed, lines)
+		close(lines)
+	}()
+	var result []string
+	for line := range lines {
+		result = append(result, line)
+	}
+	if err := <-scanErr; err != nil {
+		t.Fatalf("Couldn't scan log lines: %s", err)
+	}
+	if len(result) != 3 || result[1] != "second" {
+		t.Errorf("Unexpected lines %v", result)
+	}
+}
+
+func TestReadLinesTruncatesLongLines(t *testing.T) {
+	input := "short\n" + strings.Repeat("x", maximumLogLineLength+10) + "\nlast"
+	var result []string
+	if err := readLines(strings.NewReader(input), func(line string) bool {
+		result = append(result, line)
+		return true
+	}); err != nil {
+		t.Fatalf("A long line shouldn't fail the log: %s", err)
+	}
+	if len(result) != 3 || result[0] != "short" || len(result[1]) != maximumLogLineLength || result[2] != "last" {
+		t.Errorf("Expected 3 lines with the long one truncated, got %d lines", len(result))
+	}
+}
+
+func TestPruneLogArchives(t *testing.T) {
+	useTempDataDir(t)
+	const serverID = "test"
+	os.MkdirAll(getLogArchiveDir(serverID), os.ModePerm)
+	for i := 0; i < config.LogArchivesPerServer+2; i++ {
+		os.WriteFile(filepath.Join(getLogArchiveDir(serverID), fmt.Sprintf("20230302-0912%02d-abc.log", i)), []byte("log"), 0644)
+	}
+	if err := pruneLogArchives(serverID); err != nil {
+		t.Fatal(err)
+	}
+	archives, _ := os.ReadDir(getLogArchiveDir(serverID))
+	if len(archives) != config.LogArchivesPerServer || archives[0].Name() != "20230302-091202-abc.log" {
+		t.Errorf("Expected the %d newest archives, got %d starting with %s", config.LogArchivesPerServer, len(archives), archives[0].Name())
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
 	"github.com/instantmc/server/pkg/api/mcserverapi"
 	"github.com/instantmc/server/pkg/config"
 	"github.com/instantmc/server/pkg/db"
@@ -13,6 +14,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -23,12 +25,15 @@ var mcServerPreparationWG sync.WaitGroup
 
 // mcServerVersionPreparationWg defines a waitgroup for a mc version
 var mcServerVersionPreparationWG = map[string]*sync.WaitGroup{}
+var mcServerVersionPreparationWGMutex sync.Mutex
 
 // the variable has the following structure: map[serverID]preperation status channel
 var preparingMcContainer = map[string]chan string{}
+var preparingMcContainerMutex sync.Mutex
 
 const authEnvKey = "auth"
 const ramEnvKey = "ram"
+const worldProfileEnvKey = "world_profile"
 
 // InitMCServerManagement Setup docker connection and retrieve already running minecraft server container instances
 func InitMCServerManagement() {
@@ -56,16 +61,14 @@ func InitMCServerManagement() {
 		AddPortToUsageList(int(container.Ports[0].PublicPort))
 	}
 
-	// check if a container needs to be prepared
-	if len(preparedContainer) == 0 {
-		log.Info().Msg("Preparing a mc server in the background...")
-		PrepareMcServer(config.LatestMcVersion, models.McServerPreparationConfig{})
-	} else if len(preparedContainer) > 0 {
+	if len(preparedContainer) > 0 {
 		// we need obtain the auth keys
 		authKeys := ObtainAuthKeys(preparedContainer)
 		MergeAuthKeys(authKeys)
-		log.Info().Msg("A mc server is already prepared. Good!")
+		log.Info().Msgf("%d mc server are already prepared. Good!", len(preparedContainer))
 	}
+	// the pool prepares missing containers in the background
+	InitPreparedServerPool()
 
 	// Now we need to check for saved servers in the db
 	savedServer, err := db.GetSavedMcServer()
@@ -80,6 +83,23 @@ func InitMCServerManagement() {
 	for _, server := range savedServer {
 		// check if the current server is already running
 		targetServerID := server.ServerID
+		switch server.Status {
+		case enums.Deleting:
+			log.Warn().Msgf("Mc server %s has been deleted partially. Please delete it again", targetServerID)
+			AddPortToUsageList(server.Port)
+			continue
+		case enums.Stopping:
+			// InstantMC has been shut down while the server was stopping
+			if err := TransitionServerState(&server, enums.Stopped, SystemUserID, "Stop interrupted by InstantMC shutdown"); err != nil {
+				log.Error().Err(err).Msgf("Couldn't update state of server %s", targetServerID)
+			}
+			fallthrough
+		case enums.Stopped:
+			// stopped server keep their port until they are started again or deleted
+			AddPortToUsageList(server.Port)
+			log.Info().Msgf("⏹ Mc server %s is stopped", targetServerID)
+			continue
+		}
 		exists := false
 		for _, runningServer := range alreadyRunningServer {
 			if runningServer.ServerID == targetServerID {
@@ -92,33 +112,32 @@ func InitMCServerManagement() {
 			}
 		}
 		if exists {
+			// the container survived an InstantMC restart, but a startup may have been interrupted
+			for _, targetState := range []enums.ServerStatus{enums.Starting, enums.Running} {
+				if CanTransitionServerState(server.Status, targetState) {
+					if err := TransitionServerState(&server, targetState, SystemUserID, "Container is running"); err != nil {
+						log.Error().Err(err).Msgf("Couldn't update state of server %s", targetServerID)
+					}
+				}
+			}
 			log.Info().Msgf("☑ Mc server %s is already running", targetServerID)
 		} else {
+			if server.Status != enums.Crashed {
+				if err := TransitionServerState(&server, enums.Crashed, SystemUserID, "Container not found on startup"); err != nil {
+					log.Error().Err(err).Msgf("Couldn't update state of server %s", targetServerID)
+				}
+			}
 			log.Info().Msgf("☐ Mc server %s is starting...", targetServerID)
-			var coreBootUpWaitGroup sync.WaitGroup
-			coreBootUpWaitGroup.Add(1)
-			PrepareMcServer(server.McVersion, models.McServerPreparationConfig{
-				Port:         server.Port,
-				RamSizeMB:    server.RamSizeMB,
-				CoreBootUpWG: &coreBootUpWaitGroup,
-				ServerID:     server.ServerID,
-				AutoDeploy:   true,
-			})
-			go func() {
-				coreBootUpWaitGroup.Wait()
-				containerID, err := getContainerIDbyServerID(server.ServerID)
-				if err != nil || containerID == "" {
-					log.Error().Err(err).Msgf("Mc server %s startup failed", targetServerID)
+			go func(server models.DBMcServerContainer) {
+				if err := StartSavedMcServer(&server, SystemUserID, "InstantMC startup"); err != nil {
+					log.Error().Err(err).Msgf("Mc server %s startup failed", server.ServerID)
 					return
 				}
-				if err := db.UpdateServerContainerID(&server, containerID); err != nil {
-					log.Error().Err(err).Msgf("Couldn't update db entry for containerID for server %s", targetServerID)
-				}
-				log.Info().Msgf("☑ Mc server %s started successfully", targetServerID)
-			}()
+				log.Info().Msgf("☑ Mc server %s started successfully", server.ServerID)
+			}(server)
 		}
 	}
-
+	reserveDeletedServerPorts()
 }
 
 // Returns the container ID of the running container with given Server ID. Returns an empty string if not found
@@ -141,14 +160,20 @@ func getContainerIDbyServerID(serverID string) (string, error) {
 // If models.McServerPreparationConfig CoreBootUpWG is not nil, you need to call .Add(1) before calling PrepareMcServer
 func PrepareMcServer(mcVersion string, preparationConfig models.McServerPreparationConfig) {
 	mcServerPreparationWG.Add(1)
+	getVersionPreparationWG(mcVersion).Add(1)
+	go prepareMcServerSync(mcVersion, preparationConfig)
+}
+
+// getVersionPreparationWG Returns the waitgroup of the preparations of the mc version, it's created if it doesn't exist yet
+func getVersionPreparationWG(mcVersion string) *sync.WaitGroup {
+	mcServerVersionPreparationWGMutex.Lock()
+	defer mcServerVersionPreparationWGMutex.Unlock()
 	wg, ok := mcServerVersionPreparationWG[mcVersion]
 	if !ok {
-		var newWaitGroup sync.WaitGroup
-		wg = &newWaitGroup
+		wg = &sync.WaitGroup{}
 		mcServerVersionPreparationWG[mcVersion] = wg
 	}
-	wg.Add(1)
-	go prepareMcServerSync(mcVersion, preparationConfig)
+	return wg
 }
 
 func prepareMcServerSync(mcVersion string, preparationConfig models.McServerPreparationConfig) {
@@ -172,27 +197,53 @@ func prepareMcServerSync(mcVersion string, preparationConfig models.McServerPrep
 		targetRamSize = preparationConfig.RamSizeMB
 	}
 	env = append(env, fmt.Sprintf("ram=%d", targetRamSize))
-
-	containerName := config.WaitingReadyContainerName
-	if preparationConfig.ServerID != "" {
-		containerName = generateContainerName(preparationConfig.ServerID)
-	} else {
-		// normal container preparation
-		preparedContainer, err := GetPreparedMcServerContainer()
-		if err == nil && len(preparedContainer) > 0 {
-			containerName = config.WaitingReadyContainerNr(len(preparedContainer))
-		}
+	if preparationConfig.WorldProfile.Name != "" {
+		env = append(env, fmt.Sprintf("%s=%s", worldProfileEnvKey, preparationConfig.WorldProfile.Name))
 	}
 
 	currentPath, _ := os.Getwd()
 	targetWorldMountPath := filepath.Join(currentPath, config.DataDir, config.McWorldsDir, fmt.Sprintf("%d", port))
+	if !preparationConfig.WorldProfile.IsDefault() {
+		// e.g. the seed needs to be set before the world is generated
+		if err := WriteServerProperties(port, preparationConfig.WorldProfile.ServerProperties()); err != nil {
+			log.Error().Err(err).Msgf("Couldn't write server properties of the world on port %d", port)
+		}
+	}
 
-	containerID, err := RunContainer(config.ImageWithMcVersion(mcVersion), containerName, port, env, targetWorldMountPath, targetRamSize)
+	var containerID string
+	var err error
+	for attempt := 1; ; attempt++ {
+		var containerName string
+		if preparationConfig.ServerID != "" {
+			containerName = generateContainerName(preparationConfig.ServerID)
+		} else {
+			// normal container preparation
+			containerName = nextPreparedContainerName()
+		}
+		containerID, err = RunContainer(config.ImageWithMcVersion(mcVersion), containerName, port, env, targetWorldMountPath, targetRamSize)
+		if err == nil || attempt == config.McContainerStartAttempts {
+			break
+		}
+		log.Error().Err(err).Msgf("Couldn't start preparation docker container (attempt %d/%d). Retrying in %s...", attempt, config.McContainerStartAttempts, config.McContainerStartRetryDelay)
+		time.Sleep(config.McContainerStartRetryDelay)
+	}
 	if err != nil {
-		log.Error().Err(err).Msg("Couldn't start preparation docker container. Retrying in 2 seconds...")
-		time.Sleep(2 * time.Second)
-		RemovePortFromUsageList(port)
-		prepareMcServerSync(mcVersion, preparationConfig)
+		log.Error().Err(err).Msgf("Couldn't start preparation docker container after %d attempts", config.McContainerStartAttempts)
+		if preparationConfig.Port == 0 {
+			// a given port stays reserved by its saved server
+			RemovePortFromUsageList(port)
+		}
+		if preparationConfig.PreparationErr != nil {
+			*preparationConfig.PreparationErr = err
+		}
+		if preparationConfig.CoreBootUpWG != nil {
+			preparationConfig.CoreBootUpWG.Done()
+		}
+		if preparationConfig.PreparedWG != nil {
+			preparationConfig.PreparedWG.Done()
+		}
+		mcServerPreparationWG.Done()
+		getVersionPreparationWG(mcVersion).Done()
 		return
 	}
 
@@ -212,9 +263,34 @@ func prepareMcServerSync(mcVersion string, preparationConfig models.McServerPrep
 		PauseContainer(containerID)
 		log.Info().Msgf("A mc %s server container has been prepared", mcVersion)
 	}
+	if preparationConfig.PreparedWG != nil {
+		preparationConfig.PreparedWG.Done()
+	}
 
 	mcServerPreparationWG.Done()
-	mcServerVersionPreparationWG[mcVersion].Done()
+	getVersionPreparationWG(mcVersion).Done()
+}
+
+// nextPreparedContainerName Returns the first prepared container name which isn't used by a container yet
+func nextPreparedContainerName() string {
+	container, err := ListContainersByNameStart(config.WaitingReadyContainerName)
+	if err != nil {
+		return config.WaitingReadyContainerNr(int(time.Now().Unix()))
+	}
+	usedNames := map[string]bool{}
+	for _, curContainer := range container {
+		if len(curContainer.Names) > 0 {
+			usedNames[strings.TrimPrefix(curContainer.Names[0], "/")] = true
+		}
+	}
+	if !usedNames[config.WaitingReadyContainerName] {
+		return config.WaitingReadyContainerName
+	}
+	for nr := 1; ; nr++ {
+		if !usedNames[config.WaitingReadyContainerNr(nr)] {
+			return config.WaitingReadyContainerNr(nr)
+		}
+	}
 }
 
 func IsContainerPreparationServer(container types.Container) bool {
@@ -275,6 +351,13 @@ func GetMcServerContainer(searchConfig models.McContainerSearchConfig) ([]types.
 				continue
 			}
 		}
+		if searchForPreparedContainer && !searchConfig.AnyWorldProfile {
+			// a default world must not be handed out for a profile and vice versa
+			worldProfile, err := GetContainerWorldProfileEnv(curContainer.ID)
+			if err != nil || worldProfile != searchConfig.WorldProfile {
+				continue
+			}
+		}
 
 		targetContainer = append(targetContainer, curContainer)
 	}
@@ -344,7 +427,10 @@ func WaitForFinishedPreparing() {
 }
 
 func WaitForTargetServerPrepared(mcVersion string) {
-	if wg, ok := mcServerVersionPreparationWG[mcVersion]; ok {
+	mcServerVersionPreparationWGMutex.Lock()
+	wg, ok := mcServerVersionPreparationWG[mcVersion]
+	mcServerVersionPreparationWGMutex.Unlock()
+	if ok {
 		wg.Wait()
 	}
 }
@@ -410,27 +496,47 @@ func GenerateMcServerID(serverName string) string {
 	return generateId(serverName)
 }
 
+// serverIDRegex matches the IDs created by GenerateMcServerID
+var serverIDRegex = regexp.MustCompile("^[0-9a-f]{32}$")
+
+// IsValidServerID Returns true if the server ID could have been generated by GenerateMcServerID
+// IDs which aren't saved in the db have to be checked before they are used in paths
+func IsValidServerID(serverID string) bool {
+	return serverIDRegex.MatchString(serverID)
+}
+
 func AddPreparingServer(serverID string) chan string {
+	preparingMcContainerMutex.Lock()
+	defer preparingMcContainerMutex.Unlock()
 	preparingMcContainer[serverID] = make(chan string)
 	return preparingMcContainer[serverID]
 }
 
+// RemovePreparingServer Closes the preparation status channel of the server, so listeners stop waiting for it
+// It has to be called by the goroutine sending to the channel after its last message
 func RemovePreparingServer(serverID string) {
-	newMap := map[string]chan string{}
-	for k, v := range preparingMcContainer {
-		if k != serverID {
-			newMap[k] = v
-		}
+	preparingMcContainerMutex.Lock()
+	defer preparingMcContainerMutex.Unlock()
+	if channel, ok := preparingMcContainer[serverID]; ok {
+		close(channel)
+		delete(preparingMcContainer, serverID)
 	}
-
-	preparingMcContainer = newMap
 }
 
 func GetPreparingServerChan(serverID string) chan string {
+	preparingMcContainerMutex.Lock()
+	defer preparingMcContainerMutex.Unlock()
 	return preparingMcContainer[serverID]
 }
 
+// StartMcServer Renames the prepared container to the new server and resumes it
+// Returns ErrPreparedContainerUnavailable if the container is used by another request or has been removed in the meantime
 func StartMcServer(containerID string, name string) (models.McServerContainer, error) {
+	if !claimPreparedContainer(containerID) {
+		return models.McServerContainer{}, ErrPreparedContainerUnavailable
+	}
+	defer releasePreparedContainer(containerID)
+
 	log.Info().Msgf("Looking for prepared container %s...", containerID)
 	preparedMcServer, err := GetPreparedMcServerContainer()
 
@@ -448,9 +554,8 @@ func StartMcServer(containerID string, name string) (models.McServerContainer, e
 		}
 	}
 	if !exists {
-		err := errors.New("Couldn't find prepared container with ID " + containerID)
-		log.Error().Err(err).Send()
-		return models.McServerContainer{}, err
+		log.Warn().Msgf("Couldn't find prepared container with ID %s", containerID)
+		return models.McServerContainer{}, ErrPreparedContainerUnavailable
 	}
 
 	log.Info().Msg("Starting Mc Server with container ID " + containerID)
@@ -469,3 +574,138 @@ func StartMcServer(containerID string, name string) (models.McServerContainer, e
 	ram, _ := GetContainerRamSizeEnv(containerID)
 	return models.McServerContainer{ContainerID: containerID, Name: name, ServerID: id, Port: port, McVersion: mcVersion, Status: enums.Running, RamSizeMB: ram}, err
 }
+
+// StartSavedMcServer Creates a new container for a server which is saved in the db but has no running container
+// The mc version, port, ram size and world directory of the saved server are reused
+// Blocks until the container is up, the mc world itself continues booting in the background
+func StartSavedMcServer(server *models.DBMcServerContainer, userID uint, reason string) error {
+	if err := TransitionServerState(server, enums.Starting, userID, reason); err != nil {
+		return err
+	}
+
+	// the exited container of a crashed server still blocks the container name
+	removeStaleContainer(server)
+
+	var coreBootUpWaitGroup sync.WaitGroup
+	var preparationErr error
+	coreBootUpWaitGroup.Add(1)
+	PrepareMcServer(server.McVersion, models.McServerPreparationConfig{
+		Port:           server.Port,
+		RamSizeMB:      server.RamSizeMB,
+		CoreBootUpWG:   &coreBootUpWaitGroup,
+		PreparationErr: &preparationErr,
+		ServerID:       server.ServerID,
+		AutoDeploy:     true,
+	})
+	coreBootUpWaitGroup.Wait()
+
+	containerID, err := "", preparationErr
+	if err == nil {
+		containerID, err = getContainerIDbyServerID(server.ServerID)
+	}
+	if err == nil && containerID == "" {
+		err = errors.New("couldn't find container of server " + server.ServerID)
+	}
+	if err == nil {
+		err = db.UpdateServerContainerID(server, containerID)
+	}
+	if err != nil {
+		TransitionServerState(server, enums.Crashed, SystemUserID, "Startup failed: "+err.Error())
+		return err
+	}
+	return TransitionServerState(server, enums.Running, SystemUserID, "Container is running")
+}
+
+// removeStaleContainer Removes the container of the server which is left over e.g. after a crash, by its ID and by its name
+func removeStaleContainer(server *models.DBMcServerContainer) {
+	for _, containerRef := range []string{server.ContainerID, generateContainerName(server.ServerID)} {
+		if containerRef == "" {
+			continue
+		}
+		if err := KillContainer(containerRef); err == nil {
+			log.Info().Msgf("Removed stale container %s of server %s", containerRef, server.ServerID)
+		} else if !client.IsErrNotFound(err) {
+			log.Warn().Err(err).Msgf("Couldn't remove stale container %s of server %s", containerRef, server.ServerID)
+		}
+	}
+}
+
+// StopMcServer Gracefully shuts down the mc server and removes its container
+// The world directory, the port and the db entry are kept so the server can be started again with StartSavedMcServer
+func StopMcServer(server *models.DBMcServerContainer, userID uint, reason string) error {
+	if err := TransitionServerState(server, enums.Stopping, userID, reason); err != nil {
+		return err
+	}
+
+	containerID, err := getContainerIDbyServerID(server.ServerID)
+	if err == nil && containerID != "" {
+		log.Info().Msgf("Stopping mc server %s...", server.ServerID)
+		mcserverapi.SendMessage(server.Port, GetAuthKeyForMcServer(containerID), "Server is shutting down")
+		if err = StopContainer(containerID); err == nil {
+			err = KillContainer(containerID)
+		}
+	}
+	if err == nil {
+		err = db.UpdateServerContainerID(server, "")
+	}
+	if err != nil {
+		TransitionServerState(server, enums.Crashed, SystemUserID, "Stop failed: "+err.Error())
+		return err
+	}
+	return TransitionServerState(server, enums.Stopped, SystemUserID, "Container removed")
+}
+
+// GetMcServerDetails Merges the saved server with its owner, the docker state of its container and the status of the mc server
+// Missing containers and unreachable mc servers are no error, their details are left empty
+func GetMcServerDetails(server *models.DBMcServerContainer) (models.McServerDetails, error) {
+	details := models.McServerDetails{
+		Server:    server.ToClientJson(),
+		CreatedAt: server.CreatedAt,
+		Limits:    models.McServerResourceLimits{RamSizeMB: server.RamSizeMB},
+	}
+
+	if owner, err := db.GetUser(uint(server.UserID)); err == nil {
+		details.Owner = &models.McServerOwner{ID: owner.ID, Username: owner.Username}
+	}
+
+	if server.ContainerID == "" {
+		return details, nil
+	}
+	containerJSON, err := GetContainerStats(server.ContainerID)
+	if err != nil {
+		if client.IsErrNotFound(err) {
+			return details, nil
+		}
+		return details, err
+	}
+	state := containerJSON.State
+	details.Container = &models.McContainerDetails{
+		ID:           containerJSON.ID,
+		State:        state.Status,
+		Running:      state.Running,
+		Paused:       state.Paused,
+		OOMKilled:    state.OOMKilled,
+		ExitCode:     state.ExitCode,
+		Error:        state.Error,
+		RestartCount: containerJSON.RestartCount,
+	}
+	// docker uses RFC 3339 timestamps, the zero time if the container never started or finished
+	details.Container.StartedAt, _ = time.Parse(time.RFC3339Nano, state.StartedAt)
+	details.Container.FinishedAt, _ = time.Parse(time.RFC3339Nano, state.FinishedAt)
+	if containerJSON.HostConfig != nil {
+		details.Limits.MemoryLimitMB = int(containerJSON.HostConfig.Memory / 1000 / 1000)
+		details.Limits.CPUs = float64(containerJSON.HostConfig.NanoCPUs) / 1e9
+	}
+
+	if state.Running && !state.Paused {
+		details.UptimeSeconds = int64(time.Since(details.Container.StartedAt).Seconds())
+		if authKey := GetAuthKeyForMcServer(server.ContainerID); authKey != "" {
+			if status, err := mcserverapi.GetServerStatus(server.Port, authKey); err == nil {
+				details.McServer = &status
+			} else {
+				log.Warn().Err(err).Msgf("Couldn't fetch status of mc server %s", server.ServerID)
+			}
+		}
+	}
+	return details, nil
+}
@@ -0,0 +1,256 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/api/mojangapi"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/models"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidPlayerName = errors.New("invalid player name")
+	ErrPlayerNotListed   = errors.New("player is not on the list")
+)
+
+// playerNameRegex matches valid mc player names, the names are used in commands
+var playerNameRegex = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+
+// listOutputRegex matches the output of the `list` command of new (`1 of a max of 20`) and old (`1/20`) mc versions
+var listOutputRegex = regexp.MustCompile(`There are (\d+) ?(?:of a max of |/) ?(\d+) players online:?(.*)`)
+
+// banTimeFormat is the time format of banned-players.json
+const banTimeFormat = "2006-01-02 15:04:05 -0700"
+
+type playerListEntry interface {
+	models.WhitelistEntry | models.OpEntry | models.BanEntry
+	PlayerName() string
+}
+
+func IsValidPlayerName(name string) bool {
+	return playerNameRegex.MatchString(name)
+}
+
+// GetOnlinePlayers Runs the `list` command on the server
+// Returns ErrServerNotRunning if the server has no running container
+func GetOnlinePlayers(server *models.DBMcServerContainer) (models.OnlinePlayers, error) {
+	output, err := ExecuteMcServerCommand(server, "list")
+	if err != nil {
+		return models.OnlinePlayers{}, err
+	}
+	return parseListOutput(output)
+}
+
+// parseListOutput Parses the output of the `list` command, e.g. `There are 2 of a max of 20 players online: Steve, Alex`
+func parseListOutput(output string) (models.OnlinePlayers, error) {
+	match := listOutputRegex.FindStringSubmatch(output)
+	if match == nil {
+		return models.OnlinePlayers{}, fmt.Errorf("unexpected output of the list command: %s", output)
+	}
+	players := models.OnlinePlayers{Players: []string{}}
+	players.Count, _ = strconv.Atoi(match[1])
+	players.MaxPlayers, _ = strconv.Atoi(match[2])
+	for _, name := range strings.Split(match[3], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			players.Players = append(players.Players, name)
+		}
+	}
+	return players, nil
+}
+
+func GetWhitelist(server *models.DBMcServerContainer) ([]models.WhitelistEntry, error) {
+	return readPlayerList[models.WhitelistEntry](server, config.WhitelistFile)
+}
+
+func GetOps(server *models.DBMcServerContainer) ([]models.OpEntry, error) {
+	return readPlayerList[models.OpEntry](server, config.OpsFile)
+}
+
+func GetBannedPlayers(server *models.DBMcServerContainer) ([]models.BanEntry, error) {
+	return readPlayerList[models.BanEntry](server, config.BannedPlayersFile)
+}
+
+// AddToWhitelist Adds the player with the command `whitelist add` if the server is running, otherwise to whitelist.json
+func AddToWhitelist(server *models.DBMcServerContainer, name string) error {
+	return addToPlayerList(server, config.WhitelistFile, "whitelist add "+name, name, func(profile mojangapi.Profile) models.WhitelistEntry {
+		return models.WhitelistEntry{UUID: formatUUID(profile.ID), Name: profile.Name}
+	})
+}
+
+// RemoveFromWhitelist Returns ErrPlayerNotListed if the player isn't whitelisted
+func RemoveFromWhitelist(server *models.DBMcServerContainer, name string) error {
+	return removeFromPlayerList[models.WhitelistEntry](server, config.WhitelistFile, "whitelist remove "+name, name)
+}
+
+// AddOp Ops the player with the command `op` if the server is running, otherwise adds the player to ops.json
+func AddOp(server *models.DBMcServerContainer, name string) error {
+	return addToPlayerList(server, config.OpsFile, "op "+name, name, func(profile mojangapi.Profile) models.OpEntry {
+		return models.OpEntry{UUID: formatUUID(profile.ID), Name: profile.Name, Level: config.OpDefaultLevel}
+	})
+}
+
+// RemoveOp Returns ErrPlayerNotListed if the player isn't an op
+func RemoveOp(server *models.DBMcServerContainer, name string) error {
+	return removeFromPlayerList[models.OpEntry](server, config.OpsFile, "deop "+name, name)
+}
+
+// BanPlayer Bans the player with the command `ban` if the server is running, otherwise adds the player to banned-players.json
+// source is the name of the user who banned the player
+func BanPlayer(server *models.DBMcServerContainer, name string, reason string, source string) error {
+	command := "ban " + name
+	if reason != "" {
+		command += " " + reason
+	} else {
+		reason = "Banned by an operator."
+	}
+	return addToPlayerList(server, config.BannedPlayersFile, command, name, func(profile mojangapi.Profile) models.BanEntry {
+		return models.BanEntry{
+			UUID:    formatUUID(profile.ID),
+			Name:    profile.Name,
+			Created: time.Now().Format(banTimeFormat),
+			Source:  source,
+			Expires: "forever",
+			Reason:  reason,
+		}
+	})
+}
+
+// PardonPlayer Returns ErrPlayerNotListed if the player isn't banned
+func PardonPlayer(server *models.DBMcServerContainer, name string) error {
+	return removeFromPlayerList[models.BanEntry](server, config.BannedPlayersFile, "pardon "+name, name)
+}
+
+// addToPlayerList Runs the command if the server is running, the mc server saves the list itself
+// Otherwise the uuid of the player is resolved and the entry is added to the file of the world
+func addToPlayerList[T playerListEntry](server *models.DBMcServerContainer, fileName string, command string, name string, newEntry func(profile mojangapi.Profile) T) error {
+	if !IsValidPlayerName(name) {
+		return ErrInvalidPlayerName
+	}
+	output, err := ExecuteMcServerCommand(server, command)
+	if err == nil {
+		return checkPlayerCommandOutput(output)
+	} else if !errors.Is(err, ErrServerNotRunning) {
+		return err
+	}
+	entries, err := readPlayerList[T](server, fileName)
+	if err != nil {
+		return err
+	}
+	if containsPlayer(entries, name) {
+		return nil
+	}
+	profile, err := mojangapi.GetProfile(name)
+	if err != nil {
+		return err
+	}
+	return writePlayerList(server, fileName, append(entries, newEntry(profile)))
+}
+
+// removeFromPlayerList Runs the command if the server is running, otherwise the entry is removed from the file of the world
+func removeFromPlayerList[T playerListEntry](server *models.DBMcServerContainer, fileName string, command string, name string) error {
+	if !IsValidPlayerName(name) {
+		return ErrInvalidPlayerName
+	}
+	entries, err := readPlayerList[T](server, fileName)
+	if err != nil {
+		return err
+	}
+	if !containsPlayer(entries, name) {
+		return ErrPlayerNotListed
+	}
+	output, err := ExecuteMcServerCommand(server, command)
+	if err == nil {
+		return checkPlayerCommandOutput(output)
+	} else if !errors.Is(err, ErrServerNotRunning) {
+		return err
+	}
+	remaining := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.EqualFold(entry.PlayerName(), name) {
+			remaining = append(remaining, entry)
+		}
+	}
+	return writePlayerList(server, fileName, remaining)
+}
+
+// checkPlayerCommandOutput Returns an error if the console output of a player list command reports a failure
+// Returns mojangapi.ErrPlayerNotFound for unknown players and ErrPlayerNotListed if the player to remove isn't on the list
+// A player who is already on the list isn't an error, like for stopped servers
+func checkPlayerCommandOutput(output string) error {
+	for _, message := range config.PlayerCommandUnknownPlayerOutputs {
+		if strings.Contains(output, message) {
+			return mojangapi.ErrPlayerNotFound
+		}
+	}
+	for _, message := range config.PlayerCommandNotListedOutputs {
+		if strings.Contains(output, message) {
+			return ErrPlayerNotListed
+		}
+	}
+	for _, message := range config.PlayerCommandAlreadyListedOutputs {
+		if strings.Contains(output, message) {
+			return nil
+		}
+	}
+	for _, line := range strings.Split(output, "\n") {
+		for _, message := range config.PlayerCommandFailedOutputs {
+			if strings.Contains(line, message) {
+				return fmt.Errorf("the command failed: %s", line)
+			}
+		}
+	}
+	return nil
+}
+
+func containsPlayer[T playerListEntry](entries []T, name string) bool {
+	for _, entry := range entries {
+		if strings.EqualFold(entry.PlayerName(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPlayerList Reads a json player list of the world, a missing file is an empty list
+func readPlayerList[T playerListEntry](server *models.DBMcServerContainer, fileName string) ([]T, error) {
+	entries := []T{}
+	content, err := os.ReadFile(filepath.Join(GetMcWorldDir(server.Port), fileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("couldn't parse %s: %w", fileName, err)
+	}
+	return entries, nil
+}
+
+// writePlayerList Replaces the json player list of the world, the file is renamed into place to never leave a partial list
+func writePlayerList[T playerListEntry](server *models.DBMcServerContainer, fileName string, entries []T) error {
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(GetMcWorldDir(server.Port), fileName)
+	if err := os.WriteFile(path+".tmp", content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// formatUUID Adds the dashes to a uuid of the mojang api
+func formatUUID(id string) string {
+	if len(id) != 32 {
+		return id
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+}
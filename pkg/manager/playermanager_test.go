@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"errors"
+	"github.com/instantmc/server/pkg/api/mojangapi"
+	"golang.org/x/exp/slices"
+	"testing"
+)
+
+func TestParseListOutput(t *testing.T) {
+	cases := []struct {
+		output     string
+		count      int
+		maxPlayers int
+		players    []string
+	}{
+		{"There are 2 of a max of 20 players online: Steve, Alex", 2, 20, []string{"Steve", "Alex"}},
+		{"There are 0 of a max of 10 players online:", 0, 10, []string{}},
+		{"There are 1/20 players online:Steve", 1, 20, []string{"Steve"}},
+	}
+	for _, c := range cases {
+		players, err := parseListOutput(c.output)
+		if err != nil {
+			t.Fatalf("couldn't parse %q: %s", c.output, err)
+		}
+		if players.Count != c.count || players.MaxPlayers != c.maxPlayers || !slices.Equal(players.Players, c.players) {
+			t.Errorf("parsed %q as %+v", c.output, players)
+		}
+	}
+	if _, err := parseListOutput("Unknown command"); err == nil {
+		t.Error("expected an error for an unknown output")
+	}
+}
+
+func TestIsValidPlayerName(t *testing.T) {
+	for _, name := range []string{"Steve", "a_b_1", "abcdefghijklmnop"} {
+		if !IsValidPlayerName(name) {
+			t.Errorf("%q should be valid", name)
+		}
+	}
+	for _, name := range []string{"", "Steve Alex", "abcdefghijklmnopq", "@a", "Steve;stop"} {
+		if IsValidPlayerName(name) {
+			t.Errorf("%q should be invalid", name)
+		}
+	}
+}
+
+func TestFormatUUID(t *testing.T) {
+	if uuid := formatUUID("069a79f444e94726a5befca90e38aaf5"); uuid != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Errorf("unexpected uuid %s", uuid)
+	}
+}
+
+func TestCheckPlayerCommandOutput(t *testing.T) {
+	for output, expected := range map[string]error{
+		"[12:00:01] [Server thread/INFO]: Added Steve to the whitelist":                                   nil,
+		"[12:00:01] [Server thread/INFO]: Player is already whitelisted":                                  nil,
+		"[12:00:01] [Server thread/INFO]: That player does not exist":                                     mojangapi.ErrPlayerNotFound,
+		"[12:00:01] [Server thread/INFO]: Nothing changed. The player is not an operator":                 ErrPlayerNotListed,
+		"[12:00:01] [Server thread/INFO]: Nothing changed. The player isn't banned":                       ErrPlayerNotListed,
+		"[12:00:01] [Server thread/INFO]: Nothing changed. The player already is an operator":             nil,
+		"[12:00:01] [Server thread/INFO]: Steve joined the game\n[12:00:01] Made Steve a server operator": nil,
+	} {
+		if err := checkPlayerCommandOutput(output); !errors.Is(err, expected) {
+			t.Errorf("%q: expected %v, got %v", output, expected, err)
+		}
+	}
+	if err := checkPlayerCommandOutput("[12:00:01] [Server thread/INFO]: Could not add Steve to the whitelist"); err == nil {
+		t.Error("The failure of an old mc version should be an error")
+	}
+}
@@ -0,0 +1,126 @@
+package manager
+
+import (
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// RecordStartRequest Saves a server start request for autoscaling and statistics
+// worldProfile is the name of the requested world profile, empty for the default world
+// preparedHit must be true if the request has been served by a prepared container
+func RecordStartRequest(mcVersion string, ramSizeMB int, worldProfile string, preparedHit bool, userID uint) {
+	err := db.AddStartRequest(&models.DBStartRequest{
+		McVersion:    mcVersion,
+		RamSizeMB:    ramSizeMB,
+		WorldProfile: worldProfile,
+		PreparedHit:  preparedHit,
+		UserID:       userID,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Couldn't record start request")
+	}
+}
+
+// GetAutoscaledPoolTargets Returns the amount of prepared containers per mc version, ram size and world profile the recent demand asks for
+func GetAutoscaledPoolTargets() ([]models.PoolTarget, error) {
+	demand, err := getDemandPoolTargets()
+	if err != nil {
+		return nil, err
+	}
+	var result []models.PoolTarget
+	for key, count := range demand {
+		result = append(result, models.PoolTarget{McVersion: key.mcVersion, RamSizeMB: key.ramSizeMB, Profile: key.profile, Count: count})
+	}
+	sortPoolTargets(result)
+	return result, nil
+}
+
+// GetPoolStats Returns the hit/miss ratio of prepared containers within config.AutoscaleWindow
+func GetPoolStats() (models.PoolStats, error) {
+	requests, err := db.GetStartRequestsSince(time.Now().Add(-config.AutoscaleWindow))
+	if err != nil {
+		return models.PoolStats{}, err
+	}
+
+	stats := models.PoolStats{WindowHours: int(config.AutoscaleWindow.Hours()), Versions: []models.PoolVersionStats{}}
+	versionStats := map[poolKey]*models.PoolVersionStats{}
+	var keys []poolKey
+	for _, request := range requests {
+		key := poolKey{request.McVersion, request.RamSizeMB, request.WorldProfile}
+		curStats, ok := versionStats[key]
+		if !ok {
+			curStats = &models.PoolVersionStats{McVersion: request.McVersion, RamSizeMB: request.RamSizeMB, Profile: request.WorldProfile}
+			versionStats[key] = curStats
+			keys = append(keys, key)
+		}
+		stats.Requests++
+		curStats.Requests++
+		if request.PreparedHit {
+			stats.Hits++
+			curStats.Hits++
+		} else {
+			stats.Misses++
+			curStats.Misses++
+		}
+		if request.CreatedAt.After(curStats.LastRequest) {
+			curStats.LastRequest = request.CreatedAt
+		}
+	}
+	if stats.Requests > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(stats.Requests)
+	}
+	for _, key := range keys {
+		stats.Versions = append(stats.Versions, *versionStats[key])
+	}
+	return stats, nil
+}
+
+// pruneStartRequests Deletes the start requests which are too old to matter for autoscaling and statistics
+func pruneStartRequests() {
+	if err := db.DeleteStartRequestsBefore(time.Now().Add(-config.AutoscaleWindow)); err != nil {
+		log.Warn().Err(err).Msg("Couldn't purge old start requests")
+	}
+}
+
+// getDemandPoolTargets Calculates the autoscaled pool targets from the start requests within config.AutoscaleWindow
+func getDemandPoolTargets() (map[poolKey]int, error) {
+	if !config.AutoscaleEnabled {
+		return map[poolKey]int{}, nil
+	}
+	now := time.Now()
+	requests, err := db.GetStartRequestsSince(now.Add(-config.AutoscaleWindow))
+	if err != nil {
+		return nil, err
+	}
+	return calculateDemandPoolTargets(requests, now), nil
+}
+
+// calculateDemandPoolTargets Adds one prepared container per config.AutoscaleRequestsPerContainer requests (at least one)
+// Mc versions, ram sizes and world profiles which haven't been requested within config.AutoscaleIdleTimeout get no container
+func calculateDemandPoolTargets(requests []models.DBStartRequest, now time.Time) map[poolKey]int {
+	requestCount := map[poolKey]int{}
+	lastRequest := map[poolKey]time.Time{}
+	for _, request := range requests {
+		key := poolKey{request.McVersion, request.RamSizeMB, request.WorldProfile}
+		requestCount[key]++
+		if request.CreatedAt.After(lastRequest[key]) {
+			lastRequest[key] = request.CreatedAt
+		}
+	}
+
+	result := map[poolKey]int{}
+	for key, count := range requestCount {
+		if now.Sub(lastRequest[key]) > config.AutoscaleIdleTimeout {
+			continue
+		}
+		demand := (count + config.AutoscaleRequestsPerContainer - 1) / config.AutoscaleRequestsPerContainer
+		if demand > config.MaximumPoolSizePerTarget {
+			demand = config.MaximumPoolSizePerTarget
+		}
+		result[key] = demand
+	}
+	return result
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/models"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func startRequest(mcVersion string, createdAt time.Time) models.DBStartRequest {
+	return models.DBStartRequest{Model: gorm.Model{CreatedAt: createdAt}, McVersion: mcVersion, RamSizeMB: config.DefaultRamSize}
+}
+
+func TestCalculateDemandPoolTargets(t *testing.T) {
+	now := time.Now()
+	var requests []models.DBStartRequest
+	for i := 0; i < config.AutoscaleRequestsPerContainer+1; i++ {
+		requests = append(requests, startRequest("1.19.4", now.Add(-time.Hour)))
+	}
+	requests = append(requests, startRequest("1.18.2", now.Add(-time.Hour)))
+	requests = append(requests, startRequest("1.12.2", now.Add(-config.AutoscaleIdleTimeout-time.Hour)))
+
+	demand := calculateDemandPoolTargets(requests, now)
+
+	if count := demand[poolKey{"1.19.4", config.DefaultRamSize, ""}]; count != 2 {
+		t.Errorf("Demand for 1.19.4 is %d but it should be 2", count)
+	}
+	if count := demand[poolKey{"1.18.2", config.DefaultRamSize, ""}]; count != 1 {
+		t.Errorf("Demand for 1.18.2 is %d but it should be 1", count)
+	}
+	if _, ok := demand[poolKey{"1.12.2", config.DefaultRamSize, ""}]; ok {
+		t.Errorf("1.12.2 hasn't been requested recently and shouldn't be prepared")
+	}
+}
@@ -0,0 +1,309 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/instantmc/server/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrPreparedContainerUnavailable = errors.New("the prepared container is used or has been removed")
+
+// claimedPreparedContainers contains the IDs of the prepared containers which are consumed or removed right now
+var claimedPreparedContainers = map[string]bool{}
+var claimedPreparedContainersMutex sync.Mutex
+
+type poolKey struct {
+	mcVersion string
+	ramSizeMB int
+	profile   string
+}
+
+var poolTargets = map[poolKey]int{}
+var poolTargetsMutex sync.Mutex
+
+// poolRefillChan triggers a pool check. It has a buffer of one, multiple triggers while a check is running are merged
+var poolRefillChan = make(chan bool, 1)
+
+// InitPreparedServerPool Loads the pool targets from the db and starts preparing missing containers in the background
+// If no targets have ever been configured the pool keeps config.DefaultPoolSize containers of the latest mc version ready
+// Targets which have been set to 0 are saved, so a disabled pool stays disabled
+func InitPreparedServerPool() {
+	savedTargets, err := db.GetPoolTargets()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Couldn't fetch pool targets from db")
+	}
+	if len(savedTargets) == 0 {
+		defaultTarget := models.PoolTarget{McVersion: config.LatestMcVersion, RamSizeMB: config.DefaultRamSize, Count: config.DefaultPoolSize}
+		if err := db.SetPoolTarget(defaultTarget); err != nil {
+			log.Error().Err(err).Msg("Couldn't save default pool target")
+		}
+		savedTargets = append(savedTargets, models.DBPoolTarget{PoolTarget: defaultTarget})
+	}
+
+	poolTargetsMutex.Lock()
+	for _, target := range savedTargets {
+		if target.Count == 0 {
+			continue
+		}
+		poolTargets[poolKey{target.McVersion, target.RamSizeMB, target.Profile}] = target.Count
+	}
+	poolTargetsMutex.Unlock()
+
+	go poolWorker()
+	RefillPreparedServerPool()
+}
+
+// RefillPreparedServerPool Triggers a pool check in the background (non-blocking)
+// Should be called whenever a prepared container has been consumed
+func RefillPreparedServerPool() {
+	select {
+	case poolRefillChan <- true:
+		break
+	default:
+		break
+	}
+}
+
+// GetPoolTargets Returns the configured amount of prepared containers per mc version, ram size and world profile
+func GetPoolTargets() []models.PoolTarget {
+	poolTargetsMutex.Lock()
+	defer poolTargetsMutex.Unlock()
+
+	var result []models.PoolTarget
+	for key, count := range poolTargets {
+		result = append(result, models.PoolTarget{McVersion: key.mcVersion, RamSizeMB: key.ramSizeMB, Profile: key.profile, Count: count})
+	}
+	sortPoolTargets(result)
+	return result
+}
+
+// GetPreparedPoolState Returns the amount of currently prepared containers per mc version, ram size and world profile
+func GetPreparedPoolState() ([]models.PoolTarget, error) {
+	prepared, err := getPreparedContainerByPoolKey()
+	if err != nil {
+		return nil, err
+	}
+	var result []models.PoolTarget
+	for key, container := range prepared {
+		result = append(result, models.PoolTarget{McVersion: key.mcVersion, RamSizeMB: key.ramSizeMB, Profile: key.profile, Count: len(container)})
+	}
+	sortPoolTargets(result)
+	return result, nil
+}
+
+// SetPoolTarget Saves the target and adjusts the pool in the background. A count of 0 disables the target
+func SetPoolTarget(target models.PoolTarget) error {
+	if !slices.Contains(config.AvailableVersions, target.McVersion) {
+		return fmt.Errorf("mc_version %s not available", target.McVersion)
+	}
+	if maximumRam := GetMaximumRamPerInstance(); target.RamSizeMB <= 0 || target.RamSizeMB > maximumRam {
+		return fmt.Errorf("ram size must be between 1 and %dmb", maximumRam)
+	}
+	if target.Count < 0 || target.Count > config.MaximumPoolSizePerTarget {
+		return fmt.Errorf("count must be between 0 and %d", config.MaximumPoolSizePerTarget)
+	}
+	if target.Profile != "" && target.Count > 0 {
+		profile, err := GetWorldProfile(target.Profile)
+		if err != nil {
+			return err
+		}
+		if _, err := NormalizeWorldProfile(target.McVersion, profile); err != nil {
+			return fmt.Errorf("world profile %s isn't valid for mc %s: %w", target.Profile, target.McVersion, err)
+		}
+	}
+
+	if err := db.SetPoolTarget(target); err != nil {
+		return err
+	}
+
+	poolTargetsMutex.Lock()
+	key := poolKey{target.McVersion, target.RamSizeMB, target.Profile}
+	if target.Count == 0 {
+		delete(poolTargets, key)
+	} else {
+		poolTargets[key] = target.Count
+	}
+	poolTargetsMutex.Unlock()
+
+	RefillPreparedServerPool()
+	return nil
+}
+
+func poolWorker() {
+	ticker := time.NewTicker(config.PoolCheckInterval)
+	for {
+		select {
+		case <-poolRefillChan:
+		case <-ticker.C:
+		}
+		pruneStartRequests()
+		if err := adjustPool(); err != nil {
+			log.Error().Err(err).Msg("Couldn't adjust prepared server pool")
+		}
+	}
+}
+
+// adjustPool Removes surplus prepared containers and prepares missing ones, one after another
+func adjustPool() error {
+	prepared, err := getPreparedContainerByPoolKey()
+	if err != nil {
+		return err
+	}
+
+	// the configured targets are a minimum, the recent demand may ask for more containers
+	targets, err := getDemandPoolTargets()
+	if err != nil {
+		return err
+	}
+	poolTargetsMutex.Lock()
+	for key, count := range poolTargets {
+		if count > targets[key] {
+			targets[key] = count
+		}
+	}
+	poolTargetsMutex.Unlock()
+
+	// surplus container free their ram and disk space
+	for key, container := range prepared {
+		for i := targets[key]; i < len(container); i++ {
+			log.Info().Msgf("Removing surplus prepared mc %s container (%dmb%s)", key.mcVersion, key.ramSizeMB, key.profileSuffix())
+			if err := RemovePreparedContainer(container[i]); err != nil {
+				log.Error().Err(err).Msgf("Couldn't remove prepared container %s", container[i].ID)
+			}
+		}
+	}
+
+	for key, count := range targets {
+		if len(prepared[key]) >= count {
+			continue
+		}
+		worldProfile, err := getPoolWorldProfile(key)
+		if errors.Is(err, ErrWorldProfileNotFound) {
+			// the autoscaler may ask for a profile which has been deleted since it was requested
+			continue
+		}
+		if err != nil {
+			log.Error().Err(err).Msgf("Couldn't load world profile %s for the pool", key.profile)
+			continue
+		}
+		for i := len(prepared[key]); i < count; i++ {
+			if !hasCapacityFor(key.ramSizeMB) {
+				log.Warn().Msgf("Not enough resources to prepare mc %s container (%dmb%s) for the pool", key.mcVersion, key.ramSizeMB, key.profileSuffix())
+				break
+			}
+			log.Info().Msgf("Preparing mc %s container (%dmb%s) for the pool (%d/%d)...", key.mcVersion, key.ramSizeMB, key.profileSuffix(), i+1, count)
+			EnsureImageIsReady(config.ImageWithMcVersion(key.mcVersion))
+			var preparedWG sync.WaitGroup
+			preparedWG.Add(1)
+			PrepareMcServer(key.mcVersion, models.McServerPreparationConfig{
+				RamSizeMB:    key.ramSizeMB,
+				PreparedWG:   &preparedWG,
+				WorldProfile: worldProfile,
+			})
+			preparedWG.Wait()
+		}
+	}
+	return nil
+}
+
+// getPoolWorldProfile Returns the world profile of the pool key converted to its mc version
+func getPoolWorldProfile(key poolKey) (models.WorldProfile, error) {
+	if key.profile == "" {
+		return models.WorldProfile{}, nil
+	}
+	profile, err := GetWorldProfile(key.profile)
+	if err != nil {
+		return profile, err
+	}
+	return NormalizeWorldProfile(key.mcVersion, profile)
+}
+
+func (key poolKey) profileSuffix() string {
+	if key.profile == "" {
+		return ""
+	}
+	return ", world profile " + key.profile
+}
+
+// claimPreparedContainer Returns false if the prepared container is already consumed or removed by someone else
+func claimPreparedContainer(containerID string) bool {
+	claimedPreparedContainersMutex.Lock()
+	defer claimedPreparedContainersMutex.Unlock()
+	if claimedPreparedContainers[containerID] {
+		return false
+	}
+	claimedPreparedContainers[containerID] = true
+	return true
+}
+
+func releasePreparedContainer(containerID string) {
+	claimedPreparedContainersMutex.Lock()
+	delete(claimedPreparedContainers, containerID)
+	claimedPreparedContainersMutex.Unlock()
+}
+
+// RemovePreparedContainer Removes a prepared container including its mc world and frees its port
+// Returns ErrPreparedContainerUnavailable if the container is consumed by a start request right now or has already been consumed
+func RemovePreparedContainer(container types.Container) error {
+	if !IsContainerPreparationServer(container) {
+		return errors.New("container " + container.ID + " is not a prepared container")
+	}
+	if !claimPreparedContainer(container.ID) {
+		return ErrPreparedContainerUnavailable
+	}
+	defer releasePreparedContainer(container.ID)
+	// the container may have been renamed to a server since it has been listed
+	if containerJSON, err := GetContainerStats(container.ID); err != nil {
+		return err
+	} else if !strings.HasPrefix(containerJSON.Name, "/"+config.WaitingReadyContainerName) {
+		return ErrPreparedContainerUnavailable
+	}
+	port := utils.GetPortFromContainer(container)
+	if err := KillContainer(container.ID); err != nil {
+		return err
+	}
+	RemovePortFromUsageList(port)
+	return DeleteMcWorld(port)
+}
+
+func getPreparedContainerByPoolKey() (map[poolKey][]types.Container, error) {
+	preparedContainer, err := GetMcServerContainer(models.McContainerSearchConfig{AnyWorldProfile: true})
+	if err != nil {
+		return nil, err
+	}
+	result := map[poolKey][]types.Container{}
+	for _, container := range preparedContainer {
+		ramSize, err := GetContainerRamSizeEnv(container.ID)
+		if err != nil {
+			continue
+		}
+		worldProfile, err := GetContainerWorldProfileEnv(container.ID)
+		if err != nil {
+			continue
+		}
+		key := poolKey{utils.GetMcVersionFromContainer(container), ramSize, worldProfile}
+		result[key] = append(result[key], container)
+	}
+	return result, nil
+}
+
+func sortPoolTargets(targets []models.PoolTarget) {
+	slices.SortFunc(targets, func(a, b models.PoolTarget) bool {
+		if a.McVersion != b.McVersion {
+			return slices.Index(config.AvailableVersions, a.McVersion) < slices.Index(config.AvailableVersions, b.McVersion)
+		}
+		if a.RamSizeMB != b.RamSizeMB {
+			return a.RamSizeMB < b.RamSizeMB
+		}
+		return a.Profile < b.Profile
+	})
+}
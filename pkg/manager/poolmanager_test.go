@@ -0,0 +1,21 @@
+package manager
+
+import "testing"
+
+func TestClaimPreparedContainer(t *testing.T) {
+	if !claimPreparedContainer("abc") {
+		t.Fatal("An unused prepared container should be claimable")
+	}
+	if claimPreparedContainer("abc") {
+		t.Error("A claimed prepared container must not be claimed twice")
+	}
+	if !claimPreparedContainer("def") {
+		t.Error("Other prepared containers should stay claimable")
+	}
+	releasePreparedContainer("abc")
+	releasePreparedContainer("def")
+	if !claimPreparedContainer("abc") {
+		t.Error("A released prepared container should be claimable again")
+	}
+	releasePreparedContainer("abc")
+}
@@ -3,19 +3,33 @@ package manager
 import (
 	"github.com/instantmc/server/pkg/config"
 	"github.com/instantmc/server/pkg/utils"
+	"sync"
 )
 
+// usedPorts is shared by the api, the pool worker and the trash purger
 var usedPorts []int
+var usedPortsMutex sync.Mutex
 
+// GeneratePort Returns a random free port and adds it to the usage list, so it can't be handed out twice
 func GeneratePort() int {
-	port := utils.CreateRandomIntRange(config.PortRangeBegin, config.PortRangeEnd)
-	if IsPortBeingUsed(port) {
-		return GeneratePort()
+	usedPortsMutex.Lock()
+	defer usedPortsMutex.Unlock()
+	for {
+		port := utils.CreateRandomIntRange(config.PortRangeBegin, config.PortRangeEnd)
+		if !isPortBeingUsed(port) {
+			usedPorts = append(usedPorts, port)
+			return port
+		}
 	}
-	return port
 }
 
 func IsPortBeingUsed(port int) bool {
+	usedPortsMutex.Lock()
+	defer usedPortsMutex.Unlock()
+	return isPortBeingUsed(port)
+}
+
+func isPortBeingUsed(port int) bool {
 	for _, usedPort := range usedPorts {
 		if usedPort == port {
 			return true
@@ -24,11 +38,18 @@ func IsPortBeingUsed(port int) bool {
 	return false
 }
 
+// AddPortToUsageList Adds the port to the usage list unless it's already in use
 func AddPortToUsageList(port int) {
-	usedPorts = append(usedPorts, port)
+	usedPortsMutex.Lock()
+	defer usedPortsMutex.Unlock()
+	if !isPortBeingUsed(port) {
+		usedPorts = append(usedPorts, port)
+	}
 }
 
 func RemovePortFromUsageList(port int) {
+	usedPortsMutex.Lock()
+	defer usedPortsMutex.Unlock()
 	var tempUsedPorts []int
 
 	for _, usedPort := range usedPorts {
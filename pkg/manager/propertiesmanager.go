@@ -0,0 +1,352 @@
+package manager
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/instantmc/server/pkg/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+type propertyKind int
+
+const (
+	boolProperty propertyKind = iota
+	intProperty
+	stringProperty
+	enumProperty
+)
+
+// serverProperty describes a setting of server.properties which can be changed via the api
+// Since and Until limit the mc versions supporting the property, Until is exclusive
+// WorldGeneration properties only take effect before the world is generated
+type serverProperty struct {
+	Key             string
+	Kind            propertyKind
+	Min, Max        int
+	MaxLength       int
+	Values          []string
+	Since, Until    string
+	WorldGeneration bool
+}
+
+// serverPropertiesSchema contains every property which can be read and changed via the api
+// A key may appear multiple times with different version ranges, e.g. mc 1.14 replaced the numeric difficulties by names
+var serverPropertiesSchema = []serverProperty{
+	{Key: "motd", Kind: stringProperty, MaxLength: 150},
+	{Key: "difficulty", Kind: intProperty, Min: 0, Max: 3, Until: "1.14"},
+	{Key: "difficulty", Kind: enumProperty, Values: []string{"peaceful", "easy", "normal", "hard"}, Since: "1.14"},
+	{Key: "gamemode", Kind: intProperty, Min: 0, Max: 3, Until: "1.14"},
+	{Key: "gamemode", Kind: enumProperty, Values: []string{"survival", "creative", "adventure", "spectator"}, Since: "1.14"},
+	{Key: "hardcore", Kind: boolProperty},
+	{Key: "max-players", Kind: intProperty, Min: 1, Max: 1000},
+	{Key: "pvp", Kind: boolProperty},
+	{Key: "view-distance", Kind: intProperty, Min: 3, Max: 15, Until: "1.14"},
+	{Key: "view-distance", Kind: intProperty, Min: 3, Max: 32, Since: "1.14"},
+	{Key: "simulation-distance", Kind: intProperty, Min: 3, Max: 32, Since: "1.18"},
+	{Key: "spawn-protection", Kind: intProperty, Min: 0, Max: 1000},
+	{Key: "player-idle-timeout", Kind: intProperty, Min: 0, Max: 1440},
+	{Key: "online-mode", Kind: boolProperty},
+	{Key: "white-list", Kind: boolProperty},
+	{Key: "enforce-whitelist", Kind: boolProperty, Since: "1.13"},
+	{Key: "allow-flight", Kind: boolProperty},
+	{Key: "allow-nether", Kind: boolProperty},
+	{Key: "spawn-monsters", Kind: boolProperty},
+	{Key: "spawn-animals", Kind: boolProperty},
+	{Key: "spawn-npcs", Kind: boolProperty},
+	{Key: "level-seed", Kind: stringProperty, MaxLength: 64, WorldGeneration: true},
+	{Key: "level-type", Kind: enumProperty, Values: []string{"default", "flat", "largeBiomes", "amplified"}, Until: "1.19", WorldGeneration: true},
+	{Key: "level-type", Kind: enumProperty, Values: []string{"minecraft:normal", "minecraft:flat", "minecraft:large_biomes", "minecraft:amplified"}, Since: "1.19", WorldGeneration: true},
+	{Key: "generate-structures", Kind: boolProperty, WorldGeneration: true},
+}
+
+var ErrInvalidServerProperties = errors.New("invalid server properties")
+
+// InvalidServerPropertiesError maps the invalid properties to the reason why their value isn't accepted
+type InvalidServerPropertiesError map[string]string
+
+func (err InvalidServerPropertiesError) Error() string {
+	keys := make([]string, 0, len(err))
+	for key := range err {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	reasons := make([]string, 0, len(keys))
+	for _, key := range keys {
+		reasons = append(reasons, fmt.Sprintf("%s %s", key, err[key]))
+	}
+	return strings.Join(reasons, ", ")
+}
+
+func (err InvalidServerPropertiesError) Unwrap() error {
+	return ErrInvalidServerProperties
+}
+
+// findServerProperty Returns the schema of the property for the mc version or nil if the version doesn't support it
+func findServerProperty(mcVersion string, key string) *serverProperty {
+	for i, property := range serverPropertiesSchema {
+		if property.Key != key {
+			continue
+		}
+		if property.Since != "" && utils.CompareMcVersions(mcVersion, property.Since) < 0 {
+			continue
+		}
+		if property.Until != "" && utils.CompareMcVersions(mcVersion, property.Until) >= 0 {
+			continue
+		}
+		return &serverPropertiesSchema[i]
+	}
+	return nil
+}
+
+// ValidateServerProperties Checks the values against the schema of the mc version and returns them normalized
+// World generation properties are only accepted if worldGeneration is true
+// Returns an InvalidServerPropertiesError if a value isn't accepted
+func ValidateServerProperties(mcVersion string, values map[string]string, worldGeneration bool) (map[string]string, error) {
+	normalized := map[string]string{}
+	invalid := InvalidServerPropertiesError{}
+	for key, value := range values {
+		property := findServerProperty(mcVersion, key)
+		if property == nil {
+			invalid[key] = fmt.Sprintf("is not supported by mc %s", mcVersion)
+			continue
+		}
+		if property.WorldGeneration && !worldGeneration {
+			invalid[key] = "can only be set when the server is created"
+			continue
+		}
+		normalizedValue, reason := property.normalize(value)
+		if reason != "" {
+			invalid[key] = reason
+			continue
+		}
+		normalized[key] = normalizedValue
+	}
+	if len(invalid) > 0 {
+		return nil, invalid
+	}
+	return normalized, nil
+}
+
+// normalize Returns the value in the format of server.properties or the reason why it isn't accepted
+func (property *serverProperty) normalize(value string) (string, string) {
+	value = strings.TrimSpace(value)
+	switch property.Kind {
+	case boolProperty:
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", "must be true or false"
+		}
+		return strconv.FormatBool(parsed), ""
+	case intProperty:
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < property.Min || parsed > property.Max {
+			return "", fmt.Sprintf("must be a number between %d and %d", property.Min, property.Max)
+		}
+		return strconv.Itoa(parsed), ""
+	case enumProperty:
+		for _, allowed := range property.Values {
+			if simplifyEnumValue(value) == simplifyEnumValue(allowed) {
+				return allowed, ""
+			}
+		}
+		return "", "must be one of " + strings.Join(property.Values, ", ")
+	default:
+		if len(value) > property.MaxLength {
+			return "", fmt.Sprintf("must have at most %d characters", property.MaxLength)
+		}
+		for _, char := range value {
+			if unicode.IsControl(char) {
+				return "", "must not contain control characters"
+			}
+		}
+		return value, ""
+	}
+}
+
+// simplifyEnumValue Makes the level types of all mc versions comparable, e.g. `largeBiomes` and `minecraft:large_biomes`
+func simplifyEnumValue(value string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(value, "minecraft:"), "_", ""))
+}
+
+// typedValue Converts a value of server.properties to a bool or number if the schema says so
+func (property *serverProperty) typedValue(value string) interface{} {
+	switch property.Kind {
+	case boolProperty:
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	case intProperty:
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return value
+}
+
+func getServerPropertiesPath(port int) string {
+	return filepath.Join(GetMcWorldDir(port), config.ServerPropertiesFile)
+}
+
+// GetServerProperties Returns the properties of the schema which are set in the server.properties of the server
+// Bools and numbers are converted, other properties like the rcon password are left out
+func GetServerProperties(server *models.DBMcServerContainer) (map[string]interface{}, error) {
+	content, err := os.ReadFile(getServerPropertiesPath(server.Port))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	properties := map[string]interface{}{}
+	for key, value := range parseServerProperties(content) {
+		if property := findServerProperty(server.McVersion, key); property != nil {
+			properties[key] = property.typedValue(value)
+		}
+	}
+	return properties, nil
+}
+
+// UpdateServerProperties Validates the values and writes them to the server.properties of the server
+// The mc server reads the file on startup, a running server needs to be restarted
+func UpdateServerProperties(server *models.DBMcServerContainer, values map[string]string) error {
+	normalized, err := ValidateServerProperties(server.McVersion, values, false)
+	if err != nil {
+		return err
+	}
+	return WriteServerProperties(server.Port, normalized)
+}
+
+// WriteServerProperties Sets the values in the server.properties of the world with the port, other lines are kept
+// The values aren't validated, use ValidateServerProperties first
+func WriteServerProperties(port int, values map[string]string) error {
+	if err := os.MkdirAll(GetMcWorldDir(port), os.ModePerm); err != nil {
+		return err
+	}
+	path := getServerPropertiesPath(port)
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(path+".tmp", updateServerProperties(content, values), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// parseServerProperties Parses the `key=value` lines of a server.properties file, comments start with # or !
+func parseServerProperties(content []byte) map[string]string {
+	properties := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		if key, value, ok := parsePropertyLine(scanner.Text()); ok {
+			properties[key] = value
+		}
+	}
+	return properties
+}
+
+func parsePropertyLine(line string) (string, string, bool) {
+	line = strings.TrimLeft(line, " \t")
+	if line == "" || line[0] == '#' || line[0] == '!' {
+		return "", "", false
+	}
+	separator := strings.IndexAny(line, "=:")
+	if separator == -1 {
+		return strings.TrimSpace(line), "", true
+	}
+	return strings.TrimSpace(line[:separator]), unescapePropertyValue(strings.TrimLeft(line[separator+1:], " \t")), true
+}
+
+// updateServerProperties Replaces the lines of the changed keys and appends the new keys sorted by name
+func updateServerProperties(content []byte, values map[string]string) []byte {
+	var result bytes.Buffer
+	written := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if key, _, ok := parsePropertyLine(line); ok {
+			if value, changed := values[key]; changed {
+				line = key + "=" + escapePropertyValue(value)
+				written[key] = true
+			}
+		}
+		result.WriteString(line + "\n")
+	}
+	newKeys := []string{}
+	for key := range values {
+		if !written[key] {
+			newKeys = append(newKeys, key)
+		}
+	}
+	sort.Strings(newKeys)
+	for _, key := range newKeys {
+		result.WriteString(key + "=" + escapePropertyValue(values[key]) + "\n")
+	}
+	return result.Bytes()
+}
+
+// escapePropertyValue Escapes a value like java.util.Properties, characters outside of ASCII are written as \uXXXX
+func escapePropertyValue(value string) string {
+	var escaped strings.Builder
+	for _, char := range value {
+		switch {
+		case strings.ContainsRune(`\=:#!`, char):
+			escaped.WriteString(`\` + string(char))
+		case char < 0x20 || char > 0x7e:
+			for _, unit := range utf16.Encode([]rune{char}) {
+				escaped.WriteString(fmt.Sprintf(`\u%04X`, unit))
+			}
+		default:
+			escaped.WriteRune(char)
+		}
+	}
+	return escaped.String()
+}
+
+func unescapePropertyValue(value string) string {
+	var units []uint16
+	var unescaped strings.Builder
+	flushUnits := func() {
+		if len(units) > 0 {
+			unescaped.WriteString(string(utf16.Decode(units)))
+			units = nil
+		}
+	}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			flushUnits()
+			unescaped.WriteByte(value[i])
+			continue
+		}
+		i++
+		if value[i] == 'u' && i+4 < len(value) {
+			if unit, err := strconv.ParseUint(value[i+1:i+5], 16, 16); err == nil {
+				units = append(units, uint16(unit))
+				i += 4
+				continue
+			}
+		}
+		flushUnits()
+		switch value[i] {
+		case 't':
+			unescaped.WriteByte('\t')
+		case 'n':
+			unescaped.WriteByte('\n')
+		case 'r':
+			unescaped.WriteByte('\r')
+		case 'f':
+			unescaped.WriteByte('\f')
+		default:
+			unescaped.WriteByte(value[i])
+		}
+	}
+	flushUnits()
+	return unescaped.String()
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateServerProperties(t *testing.T) {
+	normalized, err := ValidateServerProperties("1.19.4", map[string]string{"pvp": "FALSE", "difficulty": "Hard", "max-players": "30"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if normalized["pvp"] != "false" || normalized["difficulty"] != "hard" || normalized["max-players"] != "30" {
+		t.Errorf("unexpected normalized values %v", normalized)
+	}
+
+	cases := []struct {
+		mcVersion       string
+		key, value      string
+		worldGeneration bool
+	}{
+		{"1.19.4", "max-players", "0", false},
+		{"1.19.4", "pvp", "maybe", false},
+		{"1.12.2", "difficulty", "hard", false},
+		{"1.17", "simulation-distance", "10", false},
+		{"1.19.4", "level-seed", "42", false},
+		{"1.19.4", "level-type", "superflat", true},
+		{"1.19.4", "motd", "first\nsecond", false},
+		{"1.19.4", "rcon.password", "secret", false},
+	}
+	for _, c := range cases {
+		_, err := ValidateServerProperties(c.mcVersion, map[string]string{c.key: c.value}, c.worldGeneration)
+		var invalid InvalidServerPropertiesError
+		if !errors.As(err, &invalid) || invalid[c.key] == "" || !errors.Is(err, ErrInvalidServerProperties) {
+			t.Errorf("%s=%q should be invalid for mc %s", c.key, c.value, c.mcVersion)
+		}
+	}
+
+	if _, err := ValidateServerProperties("1.12.2", map[string]string{"difficulty": "2", "level-type": "largebiomes"}, true); err != nil {
+		t.Errorf("old properties should be valid: %s", err)
+	}
+	levelTypes := map[string]string{"1.19.4": "minecraft:large_biomes", "1.16.5": "largeBiomes"}
+	for mcVersion, expected := range levelTypes {
+		normalized, err := ValidateServerProperties(mcVersion, map[string]string{"level-type": "large_biomes"}, true)
+		if err != nil || normalized["level-type"] != expected {
+			t.Errorf("level type of mc %s should be normalized to %s, got %v (%v)", mcVersion, expected, normalized, err)
+		}
+	}
+}
+
+func TestUpdateServerProperties(t *testing.T) {
+	content := []byte("#Minecraft server properties\npvp=true\nmotd=A Minecraft Server\n")
+	updated := updateServerProperties(content, map[string]string{"pvp": "false", "motd": "Ä: #1", "max-players": "30"})
+	expected := "#Minecraft server properties\npvp=false\nmotd=\\u00C4\\: \\#1\nmax-players=30\n"
+	if string(updated) != expected {
+		t.Errorf("unexpected content:\n%s", updated)
+	}
+	properties := parseServerProperties(updated)
+	if properties["motd"] != "Ä: #1" || properties["pvp"] != "false" || properties["max-players"] != "30" {
+		t.Errorf("unexpected parsed properties %v", properties)
+	}
+	if value := unescapePropertyValue(`\uD83D\uDE00 ok`); value != "😀 ok" {
+		t.Errorf("surrogate pairs should be decoded, got %q", value)
+	}
+}
@@ -0,0 +1,229 @@
+package manager
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/instantmc/server/pkg/utils"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrInvalidWorldArchive = errors.New("invalid world archive")
+	ErrNewerWorldVersion   = errors.New("the world was saved by a newer mc version")
+)
+
+// CheckBackupCompatible Returns an error wrapping ErrNewerWorldVersion if the backup was created by a newer mc version than mcVersion
+// mc servers upgrade the worlds of older versions, but can't load the worlds of newer versions
+func CheckBackupCompatible(backup *models.DBBackup, mcVersion string) error {
+	if utils.CompareMcVersions(backup.McVersion, mcVersion) > 0 {
+		return fmt.Errorf("%w: the backup is from mc %s, the server runs mc %s", ErrNewerWorldVersion, backup.McVersion, mcVersion)
+	}
+	return nil
+}
+
+// RestoreBackup Replaces the world of the server with the backup, which may belong to another server
+// The archive is extracted before a running server is stopped, the server is started again once the world is replaced
+// Returns ErrWorldBusy or an error wrapping ErrNewerWorldVersion, ErrInvalidWorldArchive or ErrInvalidStateTransition
+func RestoreBackup(server *models.DBMcServerContainer, backup *models.DBBackup, userID uint) error {
+	if err := CheckBackupCompatible(backup, server.McVersion); err != nil {
+		return err
+	}
+	wasRunning, err := checkWorldReplaceable(server)
+	if err != nil {
+		return err
+	}
+	if !lockWorld(server.ServerID) {
+		return ErrWorldBusy
+	}
+	defer unlockWorld(server.ServerID)
+
+	stagingDir, err := stageBackup(backup, server.Port)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+
+	reason := fmt.Sprintf("Restore of backup %d requested", backup.ID)
+	if wasRunning {
+		if err := StopMcServer(server, userID, reason); err != nil {
+			return err
+		}
+	}
+	restoreErr := replaceDir(GetMcWorldDir(server.Port), stagingDir)
+	if restoreErr == nil {
+		log.Info().Msgf("Restored backup %s of server %s into server %s", backup.FileName, backup.ServerID, server.ServerID)
+	}
+	if wasRunning {
+		// the previous world is kept if the restore failed
+		if err := StartSavedMcServer(server, userID, reason); err != nil && restoreErr == nil {
+			return err
+		}
+	}
+	return restoreErr
+}
+
+// CreateMcWorldFromBackup Extracts the backup as world of a new server with the port, before its container is created
+// Returns an error wrapping ErrInvalidWorldArchive if the archive is damaged or doesn't contain a world
+func CreateMcWorldFromBackup(backup *models.DBBackup, port int) error {
+	stagingDir, err := stageBackup(backup, port)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(stagingDir)
+	return replaceDir(GetMcWorldDir(port), stagingDir)
+}
+
+// checkWorldReplaceable Returns an error wrapping ErrInvalidStateTransition if the world of the server can't be replaced right now
+// A running server has to be stopped for the replacement, wasRunning tells whether it needs to be started again afterwards
+func checkWorldReplaceable(server *models.DBMcServerContainer) (wasRunning bool, err error) {
+	if server.Status == enums.Running {
+		return true, nil
+	}
+	// e.g. a server which is starting right now can't be restored
+	return false, CheckServerStateTransition(server.Status, enums.Starting)
+}
+
+// stageBackup Verifies the checksum of the backup and extracts it next to the world directory of the port
+// Returns the directory containing the extracted world
+func stageBackup(backup *models.DBBackup, port int) (string, error) {
+	file, err := os.Open(GetBackupPath(backup))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != backup.SHA256 {
+		return "", fmt.Errorf("%w: the checksum of %s doesn't match", ErrInvalidWorldArchive, backup.FileName)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	// the staging directory is on the same file system as the world, so it can be renamed into place
+	stagingDir := GetMcWorldDir(port) + ".restore"
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return "", err
+	}
+	if err := extractWorldTarGz(file, stagingDir); err != nil {
+		os.RemoveAll(stagingDir)
+		return "", err
+	}
+	return stagingDir, nil
+}
+
+// replaceDir Swaps the target directory, e.g. a world directory, with the replacement by renaming both directories
+// The replacement has to be on the same file system. The previous directory is moved back if the replacement can't be moved into place
+func replaceDir(target string, replacement string) error {
+	previousDir := target + ".previous"
+	if err := os.RemoveAll(previousDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(target, previousDir); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(replacement, target); err != nil {
+		os.Rename(previousDir, target)
+		return err
+	}
+	return os.RemoveAll(previousDir)
+}
+
+// extractWorldTarGz Extracts a gzip compressed tar archive created by WriteWorldTarGz into the target directory
+// Returns an error wrapping ErrInvalidWorldArchive if an entry isn't a regular file or directory inside the target
+// or if the archive doesn't contain the level.dat of the world
+func extractWorldTarGz(r io.Reader, targetDir string) error {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+		}
+		relativePath, ok := cleanArchivePath(header.Name)
+		if !ok {
+			return fmt.Errorf("%w: the path %s leaves the world directory", ErrInvalidWorldArchive, header.Name)
+		}
+		if relativePath == "" {
+			continue
+		}
+		target := filepath.Join(targetDir, filepath.FromSlash(relativePath))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.ModePerm); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := extractFile(tarReader, target, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			// e.g. links could point outside of the world
+			return fmt.Errorf("%w: %s is no regular file or directory", ErrInvalidWorldArchive, header.Name)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(targetDir, config.McLevelDir, "level.dat")); err != nil {
+		return fmt.Errorf("%w: %s/level.dat is missing", ErrInvalidWorldArchive, config.McLevelDir)
+	}
+	return nil
+}
+
+// cleanArchivePath Returns the slash separated path of an archive entry relative to the archive root
+// ok is false if the path is absolute or leaves the root
+func cleanArchivePath(name string) (relativePath string, ok bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) {
+		return "", false
+	}
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	if cleaned == "." {
+		return "", true
+	}
+	return cleaned, true
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package manager
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/instantmc/server/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarGz Creates a gzip compressed tar archive with the files, an empty content marks a directory
+func tarGz(t *testing.T, files map[string]string, typeflag byte) *bytes.Buffer {
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: typeflag}
+		if content == "" {
+			header.Typeflag = tar.TypeDir
+		}
+		if typeflag == tar.TypeSymlink {
+			header.Linkname, header.Size = content, 0
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			tarWriter.Write([]byte(content))
+		}
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+	return &archive
+}
+
+func TestExtractWorldTarGz(t *testing.T) {
+	worldDir := t.TempDir()
+	os.MkdirAll(filepath.Join(worldDir, "world", "region"), os.ModePerm)
+	os.WriteFile(filepath.Join(worldDir, "world", "level.dat"), []byte("level"), 0644)
+	os.WriteFile(filepath.Join(worldDir, "server.properties"), []byte("pvp=false"), 0644)
+	var archive bytes.Buffer
+	if err := WriteWorldTarGz(&archive, worldDir); err != nil {
+		t.Fatal(err)
+	}
+
+	targetDir := filepath.Join(t.TempDir(), "restored")
+	if err := extractWorldTarGz(&archive, targetDir); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, "world", "level.dat")); string(content) != "level" {
+		t.Errorf("level.dat wasn't restored, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(targetDir, "server.properties")); string(content) != "pvp=false" {
+		t.Errorf("server.properties wasn't restored, got %q", content)
+	}
+	if info, err := os.Stat(filepath.Join(targetDir, "world", "region")); err != nil || !info.IsDir() {
+		t.Errorf("The region directory wasn't restored")
+	}
+}
+
+func TestExtractWorldTarGzRejectsInvalidArchives(t *testing.T) {
+	tests := map[string]*bytes.Buffer{
+		"traversal":     tarGz(t, map[string]string{"world/level.dat": "level", "../evil": "evil"}, tar.TypeReg),
+		"absolute path": tarGz(t, map[string]string{"world/level.dat": "level", "/tmp/evil": "evil"}, tar.TypeReg),
+		"symlink":       tarGz(t, map[string]string{"world/level.dat": "/etc/passwd"}, tar.TypeSymlink),
+		"no level.dat":  tarGz(t, map[string]string{"level.dat": "level"}, tar.TypeReg),
+		"no gzip":       bytes.NewBufferString("world/level.dat"),
+	}
+	for name, archive := range tests {
+		err := extractWorldTarGz(archive, filepath.Join(t.TempDir(), "world"))
+		if !errors.Is(err, ErrInvalidWorldArchive) {
+			t.Errorf("%s: expected ErrInvalidWorldArchive, got %v", name, err)
+		}
+	}
+}
+
+func TestCheckBackupCompatible(t *testing.T) {
+	backup := models.DBBackup{McVersion: "1.19.2"}
+	for _, mcVersion := range []string{"1.19.2", "1.19.3", "1.20"} {
+		if err := CheckBackupCompatible(&backup, mcVersion); err != nil {
+			t.Errorf("A backup of mc 1.19.2 should be compatible with mc %s, got %v", mcVersion, err)
+		}
+	}
+	for _, mcVersion := range []string{"1.19.1", "1.19", "1.8.9"} {
+		if err := CheckBackupCompatible(&backup, mcVersion); !errors.Is(err, ErrNewerWorldVersion) {
+			t.Errorf("A backup of mc 1.19.2 shouldn't be compatible with mc %s, got %v", mcVersion, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/exp/slices"
+)
+
+// SystemUserID is used as actor for state transitions which are triggered by InstantMC itself
+const SystemUserID = 0
+
+var ErrInvalidStateTransition = errors.New("invalid server state transition")
+
+// allowedStateTransitions defines the lifecycle of a mc server
+// enums.Prepared is the origin state of every server, a freshly saved server starts in it
+// A server stays in enums.Deleting if its deletion fails, the deletion can be requested again from there
+var allowedStateTransitions = map[enums.ServerStatus][]enums.ServerStatus{
+	enums.Prepared:  {enums.Preparing, enums.Running},
+	enums.Preparing: {enums.Starting, enums.Crashed, enums.Deleting},
+	enums.Starting:  {enums.Running, enums.Crashed, enums.Deleting},
+	enums.Running:   {enums.Stopping, enums.Crashed, enums.Deleting},
+	enums.Stopping:  {enums.Stopped, enums.Crashed},
+	enums.Stopped:   {enums.Starting, enums.Deleting},
+	enums.Crashed:   {enums.Starting, enums.Stopped, enums.Deleting},
+	enums.Deleting:  {enums.Deleting, enums.Deleted},
+	enums.Deleted:   {enums.Stopped, enums.Purged},
+}
+
+// CanTransitionServerState Returns true if a server is allowed to change its state from `from` to `to`
+func CanTransitionServerState(from enums.ServerStatus, to enums.ServerStatus) bool {
+	return slices.Contains(allowedStateTransitions[from], to)
+}
+
+// CheckServerStateTransition Returns an error wrapping ErrInvalidStateTransition if the transition is not allowed
+func CheckServerStateTransition(from enums.ServerStatus, to enums.ServerStatus) error {
+	if !CanTransitionServerState(from, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStateTransition, from, to)
+	}
+	return nil
+}
+
+// TransitionServerState Changes the state of the server and saves the transition in the state history
+// Returns an error wrapping ErrInvalidStateTransition if the transition is not allowed
+func TransitionServerState(server *models.DBMcServerContainer, to enums.ServerStatus, userID uint, reason string) error {
+	from := server.Status
+	if err := CheckServerStateTransition(from, to); err != nil {
+		return err
+	}
+	if err := db.UpdateServerStatus(server, to); err != nil {
+		return err
+	}
+	log.Info().Msgf("Mc server %s: %s -> %s (%s)", server.ServerID, from, to, reason)
+	return db.AddServerStateTransition(&models.DBServerStateTransition{
+		ServerID: server.ServerID,
+		From:     from,
+		To:       to,
+		UserID:   userID,
+		Reason:   reason,
+	})
+}
+
+// RegisterMcServer Saves a new server in the db and records the transition into the state of mcContainer
+func RegisterMcServer(user *models.User, mcContainer *models.McServerContainer, reason string) (models.DBMcServerContainer, error) {
+	targetStatus := mcContainer.Status
+	mcContainer.Status = enums.Prepared
+	server, err := db.AddMcServerContainer(user, mcContainer)
+	if err != nil {
+		return server, err
+	}
+	err = TransitionServerState(&server, targetStatus, user.ID, reason)
+	mcContainer.Status = server.Status
+	return server, err
+}
+
+// DetectCrashedMcServer Marks every server as enums.Crashed which is saved as enums.Running but whose container isn't running
+func DetectCrashedMcServer() error {
+	savedServer, err := db.GetSavedMcServerByStatus(enums.Running)
+	if err != nil {
+		return err
+	}
+	container, err := ListContainersByNameStart(config.ContainerBaseName)
+	if err != nil {
+		return err
+	}
+
+	// map[serverID]docker container state
+	containerStates := map[string]string{}
+	for _, curContainer := range container {
+		if serverID := GetServerIDFromContainer(curContainer); serverID != "" {
+			containerStates[serverID] = curContainer.State
+		}
+	}
+
+	for _, server := range savedServer {
+		state, exists := containerStates[server.ServerID]
+		if exists && state == "running" {
+			continue
+		}
+		reason := "Container not found"
+		if exists {
+			reason = "Container is " + state
+		}
+		if err := TransitionServerState(&server, enums.Crashed, SystemUserID, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package manager
+
+import (
+	"github.com/instantmc/server/pkg/enums"
+	"testing"
+)
+
+func TestCanTransitionServerState(t *testing.T) {
+	allowed := [][2]enums.ServerStatus{
+		{enums.Prepared, enums.Running},
+		{enums.Prepared, enums.Preparing},
+		{enums.Preparing, enums.Starting},
+		{enums.Running, enums.Stopping},
+		{enums.Stopping, enums.Stopped},
+		{enums.Stopped, enums.Starting},
+		{enums.Crashed, enums.Starting},
+		{enums.Running, enums.Deleting},
+		{enums.Deleting, enums.Deleting},
+		{enums.Deleting, enums.Deleted},
+		{enums.Deleted, enums.Stopped},
+		{enums.Deleted, enums.Purged},
+	}
+	for _, transition := range allowed {
+		if !CanTransitionServerState(transition[0], transition[1]) {
+			t.Errorf("Transition %s -> %s should be allowed", transition[0], transition[1])
+		}
+	}
+
+	forbidden := [][2]enums.ServerStatus{
+		{enums.Stopped, enums.Running},
+		{enums.Running, enums.Running},
+		{enums.Running, enums.Starting},
+		{enums.Stopping, enums.Deleting},
+		{enums.Deleting, enums.Starting},
+		{enums.Deleted, enums.Starting},
+		{enums.Purged, enums.Stopped},
+	}
+	for _, transition := range forbidden {
+		if CanTransitionServerState(transition[0], transition[1]) {
+			t.Errorf("Transition %s -> %s should not be allowed", transition[0], transition[1])
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package manager
+
+import (
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// StartSessionSweeper Purges expired and revoked sessions from the db in the background every config.SessionSweepInterval
+func StartSessionSweeper() {
+	go func() {
+		for {
+			purged, err := db.DeleteExpiredSessions()
+			if err != nil {
+				log.Error().Err(err).Msg("Couldn't purge expired sessions")
+			} else if purged > 0 {
+				log.Info().Msgf("Purged %d expired sessions", purged)
+			}
+			time.Sleep(config.SessionSweepInterval)
+		}
+	}()
+}
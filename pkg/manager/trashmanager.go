@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var ErrPortInUse = errors.New("the port of the server is used by another server")
+
+// GetTrashDir Returns the directory containing the world of the deleted server
+func GetTrashDir(serverID string) string {
+	return filepath.Join(config.DataDir, config.McTrashDir, serverID)
+}
+
+// GetPurgeTime Returns when the deleted server is purged permanently
+func GetPurgeTime(server *models.DBMcServerContainer) time.Time {
+	return server.DeletedAt.Time.Add(config.DeletedServerGracePeriod)
+}
+
+// SoftDeleteMcServer Moves the world of the server into the trash and marks it as deleted, its container has to be removed before
+// The port stays reserved, so the server can be undeleted until it's purged after config.DeletedServerGracePeriod
+// Returns ErrWorldBusy if the world is backed up, restored, uploaded or downloaded right now
+func SoftDeleteMcServer(server *models.DBMcServerContainer, userID uint) error {
+	if !lockWorld(server.ServerID) {
+		return ErrWorldBusy
+	}
+	defer unlockWorld(server.ServerID)
+
+	trashDir := GetTrashDir(server.ServerID)
+	if err := os.MkdirAll(filepath.Dir(trashDir), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(trashDir); err != nil {
+		return err
+	}
+	from := server.Status
+	if err := CheckServerStateTransition(from, enums.Deleted); err != nil {
+		return err
+	}
+	// a server deleted during its preparation may have no world yet
+	worldMoved := true
+	if err := os.Rename(GetMcWorldDir(server.Port), trashDir); errors.Is(err, os.ErrNotExist) {
+		worldMoved = false
+	} else if err != nil {
+		return err
+	}
+	reason := "Moved to trash"
+	transition := models.DBServerStateTransition{ServerID: server.ServerID, From: from, To: enums.Deleted, UserID: userID, Reason: reason}
+	if err := db.SoftDeleteServer(server, enums.Deleted, &transition); err != nil {
+		// the server stays in enums.Deleting with its world, so the deletion can be retried
+		if worldMoved {
+			if renameErr := os.Rename(trashDir, GetMcWorldDir(server.Port)); renameErr != nil {
+				log.Error().Err(renameErr).Msgf("Couldn't move the world of server %s back from the trash", server.ServerID)
+			}
+		}
+		return err
+	}
+	log.Info().Msgf("Mc server %s: %s -> %s (%s)", server.ServerID, from, enums.Deleted, reason)
+	return nil
+}
+
+// UndeleteMcServer Moves the world of the deleted server back from the trash, the server is stopped afterwards
+// Returns ErrPortInUse if another server uses its port and an error wrapping ErrInvalidStateTransition if it isn't in the trash
+func UndeleteMcServer(server *models.DBMcServerContainer, userID uint) error {
+	if err := CheckServerStateTransition(server.Status, enums.Stopped); err != nil {
+		return err
+	}
+	// the port is reserved during the grace period, but the reservation of an older InstantMC version may be missing
+	savedServer, err := db.GetSavedMcServer()
+	if err != nil {
+		return err
+	}
+	for _, otherServer := range savedServer {
+		if otherServer.Port == server.Port {
+			return fmt.Errorf("%w: %s", ErrPortInUse, otherServer.ServerID)
+		}
+	}
+	worldDir := GetMcWorldDir(server.Port)
+	if _, err := os.Stat(worldDir); err == nil {
+		return fmt.Errorf("%w: the world directory %s exists", ErrPortInUse, worldDir)
+	}
+
+	if err := os.Rename(GetTrashDir(server.ServerID), worldDir); errors.Is(err, os.ErrNotExist) {
+		// the server had no world yet
+		if err := CreateMcWorld(server.Port); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if err := db.UndeleteServer(server); err != nil {
+		return err
+	}
+	AddPortToUsageList(server.Port)
+	return TransitionServerState(server, enums.Stopped, userID, "Restored from trash")
+}
+
+// PurgeDeletedMcServers Removes the worlds and archived logs of the deleted servers whose grace period expired and releases their ports
+// Their backups are kept, only their backup schedule is removed
+func PurgeDeletedMcServers(now time.Time) {
+	deletedServer, err := db.GetDeletedMcServers()
+	if err != nil {
+		log.Error().Err(err).Msg("Couldn't fetch deleted mc servers")
+		return
+	}
+	for _, server := range deletedServer {
+		if now.Before(GetPurgeTime(&server)) {
+			continue
+		}
+		if err := os.RemoveAll(GetTrashDir(server.ServerID)); err != nil {
+			log.Error().Err(err).Msgf("Couldn't remove the world of deleted server %s", server.ServerID)
+			continue
+		}
+		if err := db.DeleteBackupSchedule(server.ServerID); err != nil {
+			log.Warn().Err(err).Msgf("Couldn't delete backup schedule of server %s", server.ServerID)
+		}
+		if err := DeleteArchivedLogs(server.ServerID); err != nil {
+			log.Warn().Err(err).Msgf("Couldn't delete archived logs of server %s", server.ServerID)
+		}
+		RemovePortFromUsageList(server.Port)
+		if err := TransitionServerState(&server, enums.Purged, SystemUserID, "Grace period expired"); err != nil {
+			log.Error().Err(err).Msgf("Couldn't update state of server %s", server.ServerID)
+		}
+	}
+}
+
+// StartTrashPurger Purges the deleted servers whose grace period expired in the background every config.TrashPurgeInterval
+func StartTrashPurger() {
+	go func() {
+		for {
+			PurgeDeletedMcServers(time.Now())
+			time.Sleep(config.TrashPurgeInterval)
+		}
+	}()
+}
+
+// reserveDeletedServerPorts Reserves the ports of the servers in the trash, they can't be used until the servers are purged
+func reserveDeletedServerPorts() {
+	deletedServer, err := db.GetDeletedMcServers()
+	if err != nil {
+		log.Error().Err(err).Msg("Couldn't fetch deleted mc servers")
+		return
+	}
+	for _, server := range deletedServer {
+		AddPortToUsageList(server.Port)
+	}
+}
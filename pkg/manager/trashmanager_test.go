@@ -0,0 +1,129 @@
+package manager
+
+import (
+	"errors"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useTestDB Runs the test with an empty db in a temporary data directory
+func useTestDB(t *testing.T) {
+	useTempDataDir(t)
+	EnsureDirsExist()
+	db.Init()
+}
+
+// addTestServer Saves a server in the given state with a world containing a level.dat
+func addTestServer(t *testing.T, serverID string, port int, status enums.ServerStatus) models.DBMcServerContainer {
+	server, err := db.AddMcServerContainer(&models.User{}, &models.McServerContainer{ServerID: serverID, Port: port, Status: status, McVersion: config.LatestMcVersion})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(GetLevelDir(port), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(GetLevelDir(port), "level.dat"), []byte("level"), 0644)
+	AddPortToUsageList(port)
+	t.Cleanup(func() { RemovePortFromUsageList(port) })
+	return server
+}
+
+func TestSoftDeleteAndUndeleteMcServer(t *testing.T) {
+	useTestDB(t)
+	const port = 25011
+	server := addTestServer(t, "deleted", port, enums.Deleting)
+
+	lockWorld(server.ServerID)
+	if err := SoftDeleteMcServer(&server, 1); !errors.Is(err, ErrWorldBusy) {
+		t.Errorf("Expected ErrWorldBusy while the world is locked, got %v", err)
+	}
+	unlockWorld(server.ServerID)
+	if _, err := os.Stat(GetMcWorldDir(port)); err != nil {
+		t.Fatalf("The world of a busy server must stay in place: %v", err)
+	}
+
+	if err := SoftDeleteMcServer(&server, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(GetMcWorldDir(port)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("The world should have been moved, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(GetTrashDir(server.ServerID), config.McLevelDir, "level.dat")); err != nil {
+		t.Errorf("The world should be in the trash: %v", err)
+	}
+	deletedServer, err := db.GetDeletedMcServer(server.ServerID)
+	if err != nil || deletedServer.Status != enums.Deleted {
+		t.Fatalf("Expected the server in the trash, got %+v %v", deletedServer, err)
+	}
+	history, _ := db.GetServerStateHistory(server.ServerID)
+	if len(history) != 1 || history[0].From != enums.Deleting || history[0].To != enums.Deleted {
+		t.Errorf("Expected the transition Deleting -> Deleted, got %+v", history)
+	}
+	if !IsPortBeingUsed(port) {
+		t.Error("The port of a deleted server should stay reserved")
+	}
+
+	// another server uses the port in the meantime
+	addTestServer(t, "other", port, enums.Stopped)
+	if err := UndeleteMcServer(&deletedServer, 1); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("Expected ErrPortInUse, got %v", err)
+	}
+	if err := db.DeleteServerByID("other"); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(GetMcWorldDir(port))
+
+	if err := UndeleteMcServer(&deletedServer, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(GetLevelDir(port), "level.dat")); err != nil {
+		t.Errorf("The world should be back from the trash: %v", err)
+	}
+	savedServer, err := db.GetMcServerData(server.ServerID)
+	if err != nil || savedServer.Status != enums.Stopped {
+		t.Errorf("Expected the undeleted server to be stopped, got %+v %v", savedServer, err)
+	}
+}
+
+func TestPurgeDeletedMcServers(t *testing.T) {
+	useTestDB(t)
+	const port = 25012
+	server := addTestServer(t, "purged", port, enums.Deleting)
+	if err := SoftDeleteMcServer(&server, 1); err != nil {
+		t.Fatal(err)
+	}
+	deletedServer, err := db.GetDeletedMcServer(server.ServerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the server is kept during the grace period
+	PurgeDeletedMcServers(GetPurgeTime(&deletedServer).Add(-time.Minute))
+	if _, err := os.Stat(GetTrashDir(server.ServerID)); err != nil {
+		t.Errorf("The world must be kept during the grace period: %v", err)
+	}
+	if !IsPortBeingUsed(port) {
+		t.Error("The port must stay reserved during the grace period")
+	}
+
+	PurgeDeletedMcServers(GetPurgeTime(&deletedServer).Add(time.Minute))
+	if _, err := os.Stat(GetTrashDir(server.ServerID)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("The world should be removed after the grace period, got %v", err)
+	}
+	if IsPortBeingUsed(port) {
+		t.Error("The port should be released after the grace period")
+	}
+	if _, err := db.GetDeletedMcServer(server.ServerID); err == nil {
+		t.Error("A purged server can't be undeleted anymore")
+	}
+	history, _ := db.GetServerStateHistory(server.ServerID)
+	if len(history) == 0 || history[len(history)-1].To != enums.Purged {
+		t.Errorf("Expected the transition to Purged, got %+v", history)
+	}
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/db"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+	"regexp"
+)
+
+var (
+	ErrWorldProfileNotFound    = errors.New("world profile not found")
+	ErrInvalidWorldProfileName = errors.New("world profile names consist of 1 to 32 lower case letters, digits or dashes")
+	ErrEmptyWorldProfile       = errors.New("a world profile needs at least one setting")
+)
+
+var worldProfileNameRegex = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+func GetWorldProfiles() ([]models.WorldProfile, error) {
+	savedProfiles, err := db.GetWorldProfiles()
+	if err != nil {
+		return nil, err
+	}
+	profiles := []models.WorldProfile{}
+	for _, profile := range savedProfiles {
+		profiles = append(profiles, profile.WorldProfile)
+	}
+	return profiles, nil
+}
+
+// GetWorldProfile Returns ErrWorldProfileNotFound if no profile has the name
+func GetWorldProfile(name string) (models.WorldProfile, error) {
+	profile, err := db.GetWorldProfile(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.WorldProfile{}, fmt.Errorf("%w: %s", ErrWorldProfileNotFound, name)
+	}
+	return profile.WorldProfile, err
+}
+
+// SetWorldProfile Creates or updates a named world profile, its settings are validated for config.LatestMcVersion
+// The prepared containers of an updated profile are removed, their worlds have been generated with the old settings
+func SetWorldProfile(profile models.WorldProfile) error {
+	if !worldProfileNameRegex.MatchString(profile.Name) {
+		return ErrInvalidWorldProfileName
+	}
+	if profile.IsDefault() {
+		return ErrEmptyWorldProfile
+	}
+	if _, err := NormalizeWorldProfile(config.LatestMcVersion, profile); err != nil {
+		return err
+	}
+	if err := db.SetWorldProfile(profile); err != nil {
+		return err
+	}
+	removePreparedContainerOfProfile(profile.Name)
+	RefillPreparedServerPool()
+	return nil
+}
+
+// DeleteWorldProfile Removes the profile, its pool targets and its prepared containers
+func DeleteWorldProfile(name string) error {
+	if _, err := GetWorldProfile(name); err != nil {
+		return err
+	}
+	if err := db.DeleteWorldProfile(name); err != nil {
+		return err
+	}
+	if err := db.DeletePoolTargetsOfProfile(name); err != nil {
+		return err
+	}
+	poolTargetsMutex.Lock()
+	for key := range poolTargets {
+		if key.profile == name {
+			delete(poolTargets, key)
+		}
+	}
+	poolTargetsMutex.Unlock()
+	removePreparedContainerOfProfile(name)
+	return nil
+}
+
+// ResolveWorldProfile Returns the settings a server with the mc version should be generated with
+// The named profile (empty for the default world) is loaded first, the non empty settings of overrides replace its settings.
+// If the overrides change the profile, the result has no name because no prepared container can have this world
+func ResolveWorldProfile(mcVersion string, name string, overrides models.WorldGenerationProfile) (models.WorldProfile, error) {
+	profile := models.WorldProfile{}
+	if name != "" {
+		var err error
+		if profile, err = GetWorldProfile(name); err != nil {
+			return profile, err
+		}
+	}
+	original := profile.WorldGenerationProfile
+	if overrides.Seed != "" {
+		profile.Seed = overrides.Seed
+	}
+	if overrides.LevelType != "" {
+		profile.LevelType = overrides.LevelType
+	}
+	if overrides.GameMode != "" {
+		profile.GameMode = overrides.GameMode
+	}
+	profile, err := NormalizeWorldProfile(mcVersion, profile)
+	if err != nil {
+		return profile, err
+	}
+	if normalizedOriginal, err := NormalizeWorldProfile(mcVersion, models.WorldProfile{WorldGenerationProfile: original}); err != nil ||
+		normalizedOriginal.WorldGenerationProfile != profile.WorldGenerationProfile {
+		profile.Name = ""
+	}
+	return profile, nil
+}
+
+// NormalizeWorldProfile Validates the settings of the profile for the mc version and converts them to its format
+// e.g. the level type `flat` becomes `minecraft:flat` since mc 1.19
+// Returns an InvalidServerPropertiesError if a setting isn't valid
+func NormalizeWorldProfile(mcVersion string, profile models.WorldProfile) (models.WorldProfile, error) {
+	properties, err := ValidateServerProperties(mcVersion, profile.ServerProperties(), true)
+	if err != nil {
+		return profile, err
+	}
+	profile.Seed = properties["level-seed"]
+	profile.LevelType = properties["level-type"]
+	profile.GameMode = properties["gamemode"]
+	return profile, nil
+}
+
+// removePreparedContainerOfProfile Removes the prepared containers whose world has been generated with the profile
+func removePreparedContainerOfProfile(name string) {
+	container, err := GetMcServerContainer(models.McContainerSearchConfig{Status: enums.Prepared, WorldProfile: name})
+	if err != nil {
+		log.Error().Err(err).Msgf("Couldn't fetch prepared containers of world profile %s", name)
+		return
+	}
+	for _, curContainer := range container {
+		log.Info().Msgf("Removing prepared container %s of world profile %s", curContainer.ID, name)
+		if err := RemovePreparedContainer(curContainer); err != nil {
+			log.Error().Err(err).Msgf("Couldn't remove prepared container %s", curContainer.ID)
+		}
+	}
+}
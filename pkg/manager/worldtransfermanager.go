@@ -0,0 +1,408 @@
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"github.com/rs/zerolog/log"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrWorldNotFound       = errors.New("the server has no world yet")
+	ErrWorldUploadTooLarge = errors.New("the world upload is too large")
+)
+
+// The archive formats of world downloads and uploads
+const (
+	WorldFormatTarGz = "tar.gz"
+	WorldFormatZip   = "zip"
+)
+
+// uploadProgress contains the progress of the last world upload of each server
+var uploadProgress = map[string]models.WorldUploadProgress{}
+var uploadProgressMutex sync.Mutex
+
+// GetLevelDir Returns the directory of the level in the world directory of the server with the port
+func GetLevelDir(port int) string {
+	return filepath.Join(GetMcWorldDir(port), config.McLevelDir)
+}
+
+// CreateWorldDownload Archives the level of the server in the format WorldFormatTarGz or WorldFormatZip into a temporary file
+// The archive contains the level directory like a singleplayer save, the server configuration is left out
+// The world is locked and a running server doesn't save only while the archive is created, a slow client can't hold them
+// The caller has to close and remove the file. Returns ErrWorldBusy if the world is backed up or restored right now
+// and ErrWorldNotFound if the server hasn't generated its level yet
+func CreateWorldDownload(server *models.DBMcServerContainer, format string) (*os.File, error) {
+	if !lockWorld(server.ServerID) {
+		return nil, ErrWorldBusy
+	}
+	defer unlockWorld(server.ServerID)
+
+	savingDisabled, err := disableWorldSaving(server)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't flush the world: %w", err)
+	}
+	if savingDisabled {
+		defer func() {
+			if _, err := ExecuteMcServerCommand(server, "save-on"); err != nil {
+				log.Error().Err(err).Msgf("Couldn't enable world saving of server %s again", server.ServerID)
+			}
+		}()
+	}
+	if _, err := os.Stat(filepath.Join(GetLevelDir(server.Port), "level.dat")); errors.Is(err, os.ErrNotExist) {
+		return nil, ErrWorldNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the archive is next to the world like the archive of an upload
+	file, err := os.CreateTemp(filepath.Dir(GetMcWorldDir(server.Port)), fmt.Sprintf("%d.download.*", server.Port))
+	if err != nil {
+		return nil, err
+	}
+	prefix := config.McLevelDir + "/"
+	if format == WorldFormatZip {
+		err = writeZip(file, GetLevelDir(server.Port), prefix)
+	} else {
+		err = writeTarGz(file, GetLevelDir(server.Port), prefix)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// writeZip Writes the files of the directory as zip archive, the paths in the archive start with the prefix
+func writeZip(w io.Writer, dir string, prefix string) error {
+	zipWriter := zip.NewWriter(w)
+	err := walkWorldFiles(dir, prefix, func(name string, path string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if !info.IsDir() {
+			header.Method = zip.Deflate
+		}
+		fileWriter, err := zipWriter.CreateHeader(header)
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return copyFileTo(fileWriter, path, info.Size())
+	})
+	if err != nil {
+		return err
+	}
+	return zipWriter.Close()
+}
+
+// GetWorldUploadProgress Returns the progress of the last world upload of the server since the start of InstantMC
+func GetWorldUploadProgress(serverID string) (models.WorldUploadProgress, bool) {
+	uploadProgressMutex.Lock()
+	defer uploadProgressMutex.Unlock()
+	progress, ok := uploadProgress[serverID]
+	return progress, ok
+}
+
+func updateWorldUploadProgress(serverID string, update func(progress *models.WorldUploadProgress)) {
+	uploadProgressMutex.Lock()
+	defer uploadProgressMutex.Unlock()
+	progress := uploadProgress[serverID]
+	update(&progress)
+	if progress.TotalBytes > 0 {
+		progress.Percent = int(progress.ReceivedBytes * 100 / progress.TotalBytes)
+	}
+	if progress.Stage == enums.UploadDone {
+		progress.Percent = 100
+	}
+	progress.UpdatedAt = time.Now()
+	uploadProgress[serverID] = progress
+}
+
+// uploadProgressWriter counts the received bytes of a world upload
+type uploadProgressWriter struct {
+	serverID string
+}
+
+func (w uploadProgressWriter) Write(p []byte) (int, error) {
+	updateWorldUploadProgress(w.serverID, func(progress *models.WorldUploadProgress) {
+		progress.ReceivedBytes += int64(len(p))
+	})
+	return len(p), nil
+}
+
+// ImportWorld Replaces the level of the server with the world of an uploaded tar.gz or zip archive, e.g. a singleplayer save
+// The level.dat may be nested in the archive, the shallowest directory containing it becomes the level. The server configuration is kept
+// A running server is stopped while the level is replaced and started again afterwards. size is -1 if it's unknown
+// Returns ErrWorldBusy or an error wrapping ErrWorldUploadTooLarge, ErrInvalidWorldArchive or ErrInvalidStateTransition
+func ImportWorld(server *models.DBMcServerContainer, body io.Reader, size int64, userID uint) error {
+	wasRunning, err := checkWorldReplaceable(server)
+	if err != nil {
+		return err
+	}
+	if size > config.WorldUploadMaximumBytes {
+		return fmt.Errorf("%w: at most %d bytes are allowed", ErrWorldUploadTooLarge, config.WorldUploadMaximumBytes)
+	}
+	if !lockWorld(server.ServerID) {
+		return ErrWorldBusy
+	}
+	defer unlockWorld(server.ServerID)
+
+	updateWorldUploadProgress(server.ServerID, func(progress *models.WorldUploadProgress) {
+		*progress = models.WorldUploadProgress{Stage: enums.UploadReceiving, TotalBytes: size}
+		if size < 0 {
+			progress.TotalBytes = 0
+		}
+	})
+	err = importWorldSync(server, body, wasRunning, userID)
+	updateWorldUploadProgress(server.ServerID, func(progress *models.WorldUploadProgress) {
+		if err != nil {
+			progress.Stage = enums.UploadFailed
+			progress.Error = err.Error()
+		} else {
+			progress.Stage = enums.UploadDone
+		}
+	})
+	return err
+}
+
+func importWorldSync(server *models.DBMcServerContainer, body io.Reader, wasRunning bool, userID uint) error {
+	// the archive and the staging directory are on the same file system as the world, so the level can be renamed into place
+	archivePath := GetMcWorldDir(server.Port) + ".upload.archive"
+	stagingDir := GetMcWorldDir(server.Port) + ".upload"
+	defer os.Remove(archivePath)
+	defer os.RemoveAll(stagingDir)
+
+	archive, err := receiveWorldArchive(body, archivePath, server.ServerID)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	updateWorldUploadProgress(server.ServerID, func(progress *models.WorldUploadProgress) {
+		progress.Stage = enums.UploadExtracting
+	})
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return err
+	}
+	if err := extractUploadedWorld(archive, stagingDir); err != nil {
+		return err
+	}
+	levelRoot, err := findLevelRoot(stagingDir)
+	if err != nil {
+		return err
+	}
+	os.Remove(filepath.Join(levelRoot, "session.lock"))
+
+	updateWorldUploadProgress(server.ServerID, func(progress *models.WorldUploadProgress) {
+		progress.Stage = enums.UploadReplacing
+	})
+	reason := "World upload"
+	if wasRunning {
+		if err := StopMcServer(server, userID, reason); err != nil {
+			return err
+		}
+	}
+	replaceErr := replaceDir(GetLevelDir(server.Port), levelRoot)
+	if replaceErr == nil {
+		log.Info().Msgf("Replaced the level of server %s with an uploaded world", server.ServerID)
+	}
+	if wasRunning {
+		// the previous level is kept if the replacement failed
+		if err := StartSavedMcServer(server, userID, reason); err != nil && replaceErr == nil {
+			return err
+		}
+	}
+	return replaceErr
+}
+
+// receiveWorldArchive Writes the uploaded archive to the path and returns it opened for reading
+func receiveWorldArchive(body io.Reader, path string, serverID string) (*os.File, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	written, err := io.Copy(io.MultiWriter(file, uploadProgressWriter{serverID}), io.LimitReader(body, config.WorldUploadMaximumBytes+1))
+	if err == nil && written > config.WorldUploadMaximumBytes {
+		err = fmt.Errorf("%w: at most %d bytes are allowed", ErrWorldUploadTooLarge, config.WorldUploadMaximumBytes)
+	}
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// extractBudget limits the size of an extracted world upload
+type extractBudget struct {
+	bytes int64
+	files int
+}
+
+// extractUploadedWorld Extracts a tar.gz or zip archive into the target directory, the format is detected by its content
+// Links and other special files are skipped. Returns an error wrapping ErrInvalidWorldArchive if a path leaves the target
+// directory (zip slip) and an error wrapping ErrWorldUploadTooLarge if the extracted world exceeds the limits
+func extractUploadedWorld(archive *os.File, targetDir string) error {
+	info, err := archive.Stat()
+	if err != nil {
+		return err
+	}
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(archive, magic); err != nil {
+		return fmt.Errorf("%w: expected a tar.gz or zip archive", ErrInvalidWorldArchive)
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(targetDir, os.ModePerm); err != nil {
+		return err
+	}
+	budget := extractBudget{bytes: config.WorldUploadMaximumExtractedBytes, files: config.WorldUploadMaximumFiles}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzipReader, err := gzip.NewReader(archive)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+		}
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+			}
+			isRegular := header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA
+			if header.Typeflag != tar.TypeDir && !isRegular {
+				continue
+			}
+			if err := extractEntry(targetDir, header.Name, header.Typeflag == tar.TypeDir, tarReader, &budget); err != nil {
+				return err
+			}
+		}
+	case bytes.Equal(magic, []byte("PK\x03\x04")):
+		zipReader, err := zip.NewReader(archive, info.Size())
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+		}
+		for _, file := range zipReader.File {
+			mode := file.Mode()
+			if !mode.IsDir() && !mode.IsRegular() {
+				continue
+			}
+			if err := extractZipEntry(targetDir, file, &budget); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: expected a tar.gz or zip archive", ErrInvalidWorldArchive)
+}
+
+func extractZipEntry(targetDir string, file *zip.File, budget *extractBudget) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+	}
+	defer reader.Close()
+	return extractEntry(targetDir, file.Name, file.Mode().IsDir(), reader, budget)
+}
+
+// extractEntry Creates the directory or writes the file of an archive entry below the target directory
+func extractEntry(targetDir string, name string, isDir bool, r io.Reader, budget *extractBudget) error {
+	relativePath, ok := cleanArchivePath(name)
+	if !ok {
+		return fmt.Errorf("%w: the path %s leaves the world directory", ErrInvalidWorldArchive, name)
+	}
+	if relativePath == "" || strings.HasPrefix(relativePath, "__MACOSX/") {
+		return nil
+	}
+	budget.files--
+	if budget.files < 0 {
+		return fmt.Errorf("%w: at most %d files are allowed", ErrWorldUploadTooLarge, config.WorldUploadMaximumFiles)
+	}
+	target := filepath.Join(targetDir, filepath.FromSlash(relativePath))
+	if isDir {
+		return os.MkdirAll(target, os.ModePerm)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	// the sizes in the headers can't be trusted, the budget is checked while the content is written
+	written, err := io.Copy(file, io.LimitReader(r, budget.bytes+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidWorldArchive, err)
+	}
+	budget.bytes -= written
+	if budget.bytes < 0 {
+		return fmt.Errorf("%w: the extracted world must not exceed %d bytes", ErrWorldUploadTooLarge, config.WorldUploadMaximumExtractedBytes)
+	}
+	return nil
+}
+
+// findLevelRoot Returns the shallowest directory containing a level.dat
+// Returns an error wrapping ErrInvalidWorldArchive if there is none or several on the same depth
+func findLevelRoot(dir string) (string, error) {
+	var roots []string
+	rootDepth := -1
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.Name() != "level.dat" || !entry.Type().IsRegular() {
+			return err
+		}
+		relativeDir, err := filepath.Rel(dir, filepath.Dir(path))
+		if err != nil {
+			return err
+		}
+		depth := 0
+		if relativeDir != "." {
+			depth = strings.Count(relativeDir, string(filepath.Separator)) + 1
+		}
+		if rootDepth == -1 || depth < rootDepth {
+			roots, rootDepth = nil, depth
+		}
+		if depth == rootDepth {
+			roots = append(roots, filepath.Dir(path))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	switch len(roots) {
+	case 0:
+		return "", fmt.Errorf("%w: the archive doesn't contain a level.dat", ErrInvalidWorldArchive)
+	case 1:
+		return roots[0], nil
+	}
+	return "", fmt.Errorf("%w: the archive contains several worlds", ErrInvalidWorldArchive)
+}
@@ -0,0 +1,198 @@
+package manager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"errors"
+	"github.com/instantmc/server/pkg/enums"
+	"github.com/instantmc/server/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeArchiveFile Saves the archive as file, so it can be extracted like an upload
+func writeArchiveFile(t *testing.T, archive *bytes.Buffer) *os.File {
+	path := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(path, archive.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+func zipArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for name, content := range files {
+		fileWriter, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileWriter.Write([]byte(content))
+	}
+	zipWriter.Close()
+	return &archive
+}
+
+func TestExtractUploadedWorld(t *testing.T) {
+	archives := map[string]*bytes.Buffer{
+		"zip": zipArchive(t, map[string]string{
+			"My World/level.dat":        "level",
+			"My World/region/r.0.0.mca": "region",
+			"__MACOSX/My World/._level": "mac",
+		}),
+		"tar.gz": tarGz(t, map[string]string{
+			"My World/level.dat":        "level",
+			"My World/region/r.0.0.mca": "region",
+		}, tar.TypeReg),
+	}
+	for format, archive := range archives {
+		targetDir := filepath.Join(t.TempDir(), "upload")
+		if err := extractUploadedWorld(writeArchiveFile(t, archive), targetDir); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		levelRoot, err := findLevelRoot(targetDir)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if levelRoot != filepath.Join(targetDir, "My World") {
+			t.Errorf("%s: expected the level root My World, got %s", format, levelRoot)
+		}
+		if content, _ := os.ReadFile(filepath.Join(levelRoot, "region", "r.0.0.mca")); string(content) != "region" {
+			t.Errorf("%s: the region file wasn't extracted, got %q", format, content)
+		}
+		if _, err := os.Stat(filepath.Join(targetDir, "__MACOSX")); err == nil {
+			t.Errorf("%s: __MACOSX shouldn't be extracted", format)
+		}
+	}
+}
+
+func TestExtractUploadedWorldRejectsZipSlip(t *testing.T) {
+	for _, name := range []string{"../evil", "world/../../evil", "/tmp/evil", "..\\evil"} {
+		targetDir := filepath.Join(t.TempDir(), "upload")
+		err := extractUploadedWorld(writeArchiveFile(t, zipArchive(t, map[string]string{name: "evil"})), targetDir)
+		if !errors.Is(err, ErrInvalidWorldArchive) {
+			t.Errorf("%s: expected ErrInvalidWorldArchive, got %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(filepath.Dir(targetDir), "evil")); err == nil {
+			t.Errorf("%s: the file was written outside of the target directory", name)
+		}
+	}
+
+	err := extractUploadedWorld(writeArchiveFile(t, bytes.NewBufferString("no archive")), t.TempDir())
+	if !errors.Is(err, ErrInvalidWorldArchive) {
+		t.Errorf("Expected ErrInvalidWorldArchive for an unknown format, got %v", err)
+	}
+}
+
+func TestExtractEntryBudget(t *testing.T) {
+	targetDir := t.TempDir()
+	budget := extractBudget{bytes: 10, files: 2}
+	if err := extractEntry(targetDir, "a", false, bytes.NewBufferString("12345"), &budget); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractEntry(targetDir, "b", false, bytes.NewBufferString("123456"), &budget); !errors.Is(err, ErrWorldUploadTooLarge) {
+		t.Errorf("Expected ErrWorldUploadTooLarge for too many bytes, got %v", err)
+	}
+	budget = extractBudget{bytes: 10, files: 1}
+	extractEntry(targetDir, "c", true, nil, &budget)
+	if err := extractEntry(targetDir, "d", true, nil, &budget); !errors.Is(err, ErrWorldUploadTooLarge) {
+		t.Errorf("Expected ErrWorldUploadTooLarge for too many files, got %v", err)
+	}
+}
+
+func TestFindLevelRoot(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "saves", "a", "DIM1"), os.ModePerm)
+	os.WriteFile(filepath.Join(dir, "saves", "a", "level.dat"), []byte("level"), 0644)
+	os.WriteFile(filepath.Join(dir, "saves", "a", "DIM1", "level.dat"), []byte("level"), 0644)
+	if root, err := findLevelRoot(dir); err != nil || root != filepath.Join(dir, "saves", "a") {
+		t.Errorf("Expected the shallowest level.dat, got %s %v", root, err)
+	}
+
+	os.MkdirAll(filepath.Join(dir, "saves", "b"), os.ModePerm)
+	os.WriteFile(filepath.Join(dir, "saves", "b", "level.dat"), []byte("level"), 0644)
+	if _, err := findLevelRoot(dir); !errors.Is(err, ErrInvalidWorldArchive) {
+		t.Errorf("Expected ErrInvalidWorldArchive for several worlds, got %v", err)
+	}
+	if _, err := findLevelRoot(t.TempDir()); !errors.Is(err, ErrInvalidWorldArchive) {
+		t.Errorf("Expected ErrInvalidWorldArchive without level.dat, got %v", err)
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	levelDir := t.TempDir()
+	os.MkdirAll(filepath.Join(levelDir, "region"), os.ModePerm)
+	os.WriteFile(filepath.Join(levelDir, "level.dat"), []byte("level"), 0644)
+	os.WriteFile(filepath.Join(levelDir, "session.lock"), []byte("lock"), 0644)
+
+	var archive bytes.Buffer
+	if err := writeZip(&archive, levelDir, "world/"); err != nil {
+		t.Fatal(err)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, file := range zipReader.File {
+		names[file.Name] = true
+	}
+	for _, name := range []string{"world/level.dat", "world/region/"} {
+		if !names[name] {
+			t.Errorf("%s is missing, got %v", name, names)
+		}
+	}
+	if names["world/session.lock"] {
+		t.Errorf("session.lock shouldn't be archived")
+	}
+}
+
+func TestCreateWorldDownload(t *testing.T) {
+	useTempDataDir(t)
+	server := models.DBMcServerContainer{}
+	server.ServerID, server.Port, server.Status = "test", 25001, enums.Stopped
+	if err := CreateMcWorld(server.Port); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateWorldDownload(&server, WorldFormatZip); !errors.Is(err, ErrWorldNotFound) {
+		t.Errorf("Expected ErrWorldNotFound without level.dat, got %v", err)
+	}
+	os.MkdirAll(GetLevelDir(server.Port), os.ModePerm)
+	os.WriteFile(filepath.Join(GetLevelDir(server.Port), "level.dat"), []byte("level"), 0644)
+
+	lockWorld(server.ServerID)
+	if _, err := CreateWorldDownload(&server, WorldFormatZip); !errors.Is(err, ErrWorldBusy) {
+		t.Errorf("Expected ErrWorldBusy while the world is locked, got %v", err)
+	}
+	unlockWorld(server.ServerID)
+
+	archive, err := CreateWorldDownload(&server, WorldFormatZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	// the world is unlocked before the archive is sent
+	if !lockWorld(server.ServerID) {
+		t.Error("The world should be unlocked once the archive is created")
+	}
+	unlockWorld(server.ServerID)
+	info, err := archive.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipReader, err := zip.NewReader(archive, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zipReader.File) != 1 || zipReader.File[0].Name != "world/level.dat" {
+		t.Errorf("Expected world/level.dat in the archive, got %v", zipReader.File)
+	}
+}
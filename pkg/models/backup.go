@@ -0,0 +1,76 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// DBBackup is a compressed archive of the world of a server, FileName is relative to the backup directory of the server
+// McVersion is the version of the server when the backup was created
+type DBBackup struct {
+	gorm.Model
+	ServerID  string `gorm:"index"`
+	FileName  string
+	McVersion string
+	SizeBytes int64
+	SHA256    string
+	Scheduled bool
+	UserID    uint
+}
+
+type ClientBackup struct {
+	ID        uint      `json:"id"`
+	ServerID  string    `json:"server_id"`
+	CreatedAt time.Time `json:"created_at"`
+	McVersion string    `json:"mc_version"`
+	SizeBytes int64     `json:"size_bytes"`
+	SHA256    string    `json:"sha256"`
+	Scheduled bool      `json:"scheduled"`
+	UserID    uint      `json:"user_id"`
+}
+
+func (backup *DBBackup) ToClientJson() ClientBackup {
+	return ClientBackup{
+		ID:        backup.ID,
+		ServerID:  backup.ServerID,
+		CreatedAt: backup.CreatedAt,
+		McVersion: backup.McVersion,
+		SizeBytes: backup.SizeBytes,
+		SHA256:    backup.SHA256,
+		Scheduled: backup.Scheduled,
+		UserID:    backup.UserID,
+	}
+}
+
+// BackupRetention defines which backups of a server are kept, every backup kept by at least one rule survives
+type BackupRetention struct {
+	// KeepLast keeps the newest backups
+	KeepLast int `json:"keep_last"`
+	// KeepDaily keeps the newest backup of each of the last days with backups
+	KeepDaily int `json:"keep_daily"`
+	// KeepWeekly keeps the newest backup of each of the last ISO weeks with backups
+	KeepWeekly int `json:"keep_weekly"`
+}
+
+// DBBackupSchedule creates backups of a running server whenever the cron expression is due
+type DBBackupSchedule struct {
+	gorm.Model
+	ServerID  string `gorm:"uniqueIndex"`
+	Cron      string
+	LastRunAt *time.Time
+	BackupRetention
+}
+
+type ClientBackupSchedule struct {
+	Cron      string     `json:"cron"`
+	LastRunAt *time.Time `json:"last_run_at"`
+	BackupRetention
+}
+
+func (schedule *DBBackupSchedule) ToClientJson() ClientBackupSchedule {
+	return ClientBackupSchedule{
+		Cron:            schedule.Cron,
+		LastRunAt:       schedule.LastRunAt,
+		BackupRetention: schedule.BackupRetention,
+	}
+}
@@ -1,18 +1,193 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"github.com/instantmc/server/pkg/config"
+	"github.com/instantmc/server/pkg/enums"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
 
 type User struct {
 	gorm.Model
 	Username string
 	Password string
+	Role     enums.UserRole
+	// MustChangePassword restricts the user to the password change until a new password is set
+	MustChangePassword bool
+}
+
+type ClientUser struct {
+	ID                 uint   `json:"id"`
+	Username           string `json:"username"`
+	Role               string `json:"role"`
+	MustChangePassword bool   `json:"password_change_required"`
+}
+
+func (user *User) ToClientJson() ClientUser {
+	return ClientUser{
+		ID:                 user.ID,
+		Username:           user.Username,
+		Role:               user.Role.String(),
+		MustChangePassword: user.MustChangePassword,
+	}
 }
 
 type Session struct {
 	gorm.Model
-	Token  string
-	UserID int
-	User   User
+	Token      string
+	UserID     int
+	User       User
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	ClientIP   string
+	UserAgent  string
+}
+
+// IsExpired Returns true if the session hasn't been used for config.SessionIdleTimeout or has reached its absolute expiry
+// Sessions created before expiry existed are treated as if they were last used when they were created
+func (session *Session) IsExpired(now time.Time) bool {
+	lastUsedAt := session.LastUsedAt
+	if lastUsedAt.IsZero() {
+		lastUsedAt = session.CreatedAt
+	}
+	expiresAt := session.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = session.CreatedAt.Add(config.SessionAbsoluteTimeout)
+	}
+	return now.After(expiresAt) || now.Sub(lastUsedAt) > config.SessionIdleTimeout
+}
+
+type ClientSession struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	ClientIP   string    `json:"client_ip"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+}
+
+func (session *Session) ToClientJson(current bool) ClientSession {
+	return ClientSession{
+		ID:         session.ID,
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+		ExpiresAt:  session.ExpiresAt,
+		ClientIP:   session.ClientIP,
+		UserAgent:  session.UserAgent,
+		Current:    current,
+	}
+}
+
+// LoginFailure counts the failed logins of a username or a client ip
+// Logins are refused until LockedUntil
+type LoginFailure struct {
+	gorm.Model
+	Kind          enums.LoginFailureKind `gorm:"index:idx_login_failure_subject"`
+	Subject       string                 `gorm:"index:idx_login_failure_subject"`
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// IsLocked Returns true if logins of the subject are refused at the moment
+func (failure *LoginFailure) IsLocked(now time.Time) bool {
+	return now.Before(failure.LockedUntil)
+}
+
+// IsStale Returns true if the failed logins are old enough to be forgotten
+func (failure *LoginFailure) IsStale(now time.Time) bool {
+	return !failure.IsLocked(now) && now.Sub(failure.LastFailureAt) > config.LoginFailureResetTime
+}
+
+type ClientLoginFailure struct {
+	ID            uint                   `json:"id"`
+	Kind          enums.LoginFailureKind `json:"kind"`
+	Subject       string                 `json:"subject"`
+	Failures      int                    `json:"failures"`
+	LastFailureAt time.Time              `json:"last_failure_at"`
+	LockedUntil   time.Time              `json:"locked_until"`
+	Locked        bool                   `json:"locked"`
+}
+
+func (failure *LoginFailure) ToClientJson(now time.Time) ClientLoginFailure {
+	return ClientLoginFailure{
+		ID:            failure.ID,
+		Kind:          failure.Kind,
+		Subject:       failure.Subject,
+		Failures:      failure.Failures,
+		LastFailureAt: failure.LastFailureAt,
+		LockedUntil:   failure.LockedUntil,
+		Locked:        failure.IsLocked(now),
+	}
+}
+
+// APIToken is a long-lived token for automation which acts on behalf of its user, limited to its scopes
+// If ServerID is not empty, the token can only access this server. A zero ExpiresAt never expires
+type APIToken struct {
+	gorm.Model
+	Name       string
+	Token      string `gorm:"index"`
+	UserID     int
+	User       User
+	Scopes     string // comma separated enums.APIScope
+	ServerID   string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+func (token *APIToken) GetScopes() []enums.APIScope {
+	var scopes []enums.APIScope
+	for _, scope := range strings.Split(token.Scopes, ",") {
+		if scope != "" {
+			scopes = append(scopes, enums.APIScope(scope))
+		}
+	}
+	return scopes
+}
+
+func (token *APIToken) HasScope(scope enums.APIScope) bool {
+	for _, curScope := range token.GetScopes() {
+		if curScope == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (token *APIToken) IsExpired(now time.Time) bool {
+	return !token.ExpiresAt.IsZero() && now.After(token.ExpiresAt)
+}
+
+type ClientAPIToken struct {
+	ID         uint             `json:"id"`
+	Name       string           `json:"name"`
+	Scopes     []enums.APIScope `json:"scopes"`
+	ServerID   string           `json:"server_id"`
+	CreatedAt  time.Time        `json:"created_at"`
+	LastUsedAt time.Time        `json:"last_used_at"`
+	ExpiresAt  *time.Time       `json:"expires_at"`
+}
+
+func (token *APIToken) ToClientJson() ClientAPIToken {
+	var expiresAt *time.Time
+	if !token.ExpiresAt.IsZero() {
+		expiresAt = &token.ExpiresAt
+	}
+	scopes := token.GetScopes()
+	if scopes == nil {
+		scopes = []enums.APIScope{}
+	}
+	return ClientAPIToken{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     scopes,
+		ServerID:   token.ServerID,
+		CreatedAt:  token.CreatedAt,
+		LastUsedAt: token.LastUsedAt,
+		ExpiresAt:  expiresAt,
+	}
 }
 
 type DBMcServerContainer struct {
@@ -20,3 +195,114 @@ type DBMcServerContainer struct {
 	UserID int
 	McServerContainer
 }
+
+// IsOwnedBy Returns true if the user owns the server
+func (server *DBMcServerContainer) IsOwnedBy(user *User) bool {
+	return server.UserID == int(user.ID)
+}
+
+type DBPoolTarget struct {
+	gorm.Model
+	PoolTarget
+}
+
+// DBStartRequest is recorded for every server start request
+// PreparedHit is true if the request has been served by a prepared container
+type DBStartRequest struct {
+	gorm.Model
+	McVersion    string
+	RamSizeMB    int
+	WorldProfile string `gorm:"not null;default:''"`
+	PreparedHit  bool
+	UserID       uint
+}
+
+// DBServerStateTransition is one entry of the state history of a mc server
+// UserID is 0 if the transition has been triggered by InstantMC itself
+type DBServerStateTransition struct {
+	gorm.Model
+	ServerID string `gorm:"index"`
+	From     enums.ServerStatus
+	To       enums.ServerStatus
+	UserID   uint
+	Reason   string
+}
+
+type ClientServerStateTransition struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	UserID uint      `json:"user_id"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+func (transition *DBServerStateTransition) ToClientJson() ClientServerStateTransition {
+	return ClientServerStateTransition{
+		From:   transition.From.String(),
+		To:     transition.To.String(),
+		UserID: transition.UserID,
+		Reason: transition.Reason,
+		Time:   transition.CreatedAt,
+	}
+}
+
+// AuditEntry records a mutating api request. Audit entries are never updated or deleted
+// UserID and the token IDs are 0 if the request couldn't be authenticated
+type AuditEntry struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index"`
+	UserID     uint      `gorm:"index"`
+	Username   string
+	SessionID  uint
+	APITokenID uint
+	ClientIP   string
+	Method     string
+	Route      string
+	ServerID   string `gorm:"index"`
+	Status     int
+	Success    bool
+	// Details describes actions which aren't requests of their own, e.g. a console command
+	Details string
+}
+
+type ClientAuditEntry struct {
+	ID         uint      `json:"id"`
+	Time       time.Time `json:"time"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	SessionID  uint      `json:"session_id,omitempty"`
+	APITokenID uint      `json:"api_token_id,omitempty"`
+	ClientIP   string    `json:"client_ip"`
+	Method     string    `json:"method"`
+	Route      string    `json:"route"`
+	ServerID   string    `json:"server_id,omitempty"`
+	Status     int       `json:"status"`
+	Success    bool      `json:"success"`
+	Details    string    `json:"details,omitempty"`
+}
+
+func (entry *AuditEntry) ToClientJson() ClientAuditEntry {
+	return ClientAuditEntry{
+		ID:         entry.ID,
+		Time:       entry.CreatedAt,
+		UserID:     entry.UserID,
+		Username:   entry.Username,
+		SessionID:  entry.SessionID,
+		APITokenID: entry.APITokenID,
+		ClientIP:   entry.ClientIP,
+		Method:     entry.Method,
+		Route:      entry.Route,
+		ServerID:   entry.ServerID,
+		Status:     entry.Status,
+		Success:    entry.Success,
+		Details:    entry.Details,
+	}
+}
+
+// AuditFilter restricts the audit entries returned by the db. Empty fields don't filter
+type AuditFilter struct {
+	Username string
+	ServerID string
+	Since    time.Time
+	Until    time.Time
+}
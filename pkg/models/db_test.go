@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/instantmc/server/pkg/config"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestSessionIsExpired(t *testing.T) {
+	now := time.Now()
+	activeSession := Session{LastUsedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	if activeSession.IsExpired(now) {
+		t.Errorf("Recently used session shouldn't be expired")
+	}
+	idleSession := Session{LastUsedAt: now.Add(-config.SessionIdleTimeout - time.Hour), ExpiresAt: now.Add(time.Hour)}
+	if !idleSession.IsExpired(now) {
+		t.Errorf("Idle session should be expired")
+	}
+	oldSession := Session{LastUsedAt: now, ExpiresAt: now.Add(-time.Minute)}
+	if !oldSession.IsExpired(now) {
+		t.Errorf("Session after its absolute expiry should be expired")
+	}
+	legacySession := Session{Model: gorm.Model{CreatedAt: now.Add(-config.SessionAbsoluteTimeout - time.Hour)}}
+	if !legacySession.IsExpired(now) {
+		t.Errorf("Session without expiry should expire relative to its creation")
+	}
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// ServerFile is a file or directory in the world directory of a server, Path is relative to the world directory
+type ServerFile struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"`
+	IsDir      bool      `json:"is_dir"`
+	SizeBytes  int64     `json:"size_bytes"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
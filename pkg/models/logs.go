@@ -0,0 +1,31 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+type LogLine struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+// LogQuery filters the log of a mc server. Zero values don't filter
+// Tail returns only the last lines after all other filters have been applied
+type LogQuery struct {
+	Since time.Time
+	Until time.Time
+	Grep  *regexp.Regexp
+	Tail  int
+}
+
+// Matches Returns true if the line is within the time range of the query and matches its pattern
+func (query *LogQuery) Matches(line LogLine) bool {
+	if !query.Since.IsZero() && line.Time.Before(query.Since) {
+		return false
+	}
+	if !query.Until.IsZero() && line.Time.After(query.Until) {
+		return false
+	}
+	return query.Grep == nil || query.Grep.MatchString(line.Line)
+}
@@ -20,15 +20,24 @@ func (mcServer *McServerContainer) Self() *McServerContainer {
 	return mcServer
 }
 
-func (mcServer *McServerContainer) ToClientJson() interface{} {
-	return struct {
-		ServerID  string `json:"server_id"`
-		Name      string `json:"name"`
-		McVersion string `json:"mc_version"`
-		Port      int    `json:"port"`
-		RamSizeMB int    `json:"ram_size_mb"`
-		Status    string `json:"status"`
-	}{
+type ClientMcServer struct {
+	ServerID  string `json:"server_id"`
+	Name      string `json:"name"`
+	McVersion string `json:"mc_version"`
+	Port      int    `json:"port"`
+	RamSizeMB int    `json:"ram_size_mb"`
+	Status    string `json:"status"`
+}
+
+// ClientDeletedMcServer is a server in the trash, it can be undeleted until PurgeAt
+type ClientDeletedMcServer struct {
+	ClientMcServer
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+func (mcServer *McServerContainer) ToClientJson() ClientMcServer {
+	return ClientMcServer{
 		ServerID:  mcServer.ServerID,
 		Name:      mcServer.Name,
 		McVersion: mcServer.McVersion,
@@ -44,25 +53,76 @@ type ServerStatus struct {
 	} `json:"server"`
 }
 
+// McServerDetails merges the saved server with the live state of its container and of the mc server inside
+// Owner is nil if the user has been deleted, Container is nil if the server has no container (e.g. stopped) and
+// McServer is nil if the mc server inside the container can't be reached
+type McServerDetails struct {
+	Server        ClientMcServer         `json:"server"`
+	CreatedAt     time.Time              `json:"created_at"`
+	Owner         *McServerOwner         `json:"owner"`
+	Container     *McContainerDetails    `json:"container"`
+	McServer      *ServerStatus          `json:"mc_server"`
+	UptimeSeconds int64                  `json:"uptime_seconds"`
+	Limits        McServerResourceLimits `json:"limits"`
+}
+
+type McServerOwner struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// McContainerDetails is the docker state of a mc server container
+type McContainerDetails struct {
+	ID           string    `json:"id"`
+	State        string    `json:"state"`
+	Running      bool      `json:"running"`
+	Paused       bool      `json:"paused"`
+	OOMKilled    bool      `json:"oom_killed"`
+	ExitCode     int       `json:"exit_code"`
+	Error        string    `json:"error,omitempty"`
+	RestartCount int       `json:"restart_count"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+// McServerResourceLimits RamSizeMB is the configured ram of the mc server, MemoryLimitMB the memory limit of its container
+// CPUs is 0 if the container may use all cpus
+type McServerResourceLimits struct {
+	RamSizeMB     int     `json:"ram_size_mb"`
+	MemoryLimitMB int     `json:"memory_limit_mb"`
+	CPUs          float64 `json:"cpus"`
+}
+
 // McServerPreparationConfig
 // CoreBootUpWG waits until the http server started
+// PreparedWG waits until the mc world is ready (and the container is paused if AutoDeploy is false)
 // If AutoDeploy is set to false the container will pause and wait until it is picked up
 type McServerPreparationConfig struct {
 	Port         int
 	AuthKey      string
 	RamSizeMB    int
 	CoreBootUpWG *sync.WaitGroup
+	PreparedWG   *sync.WaitGroup
 	ServerID     string
 	AutoDeploy   bool
+	// PreparationErr receives the error if the container couldn't be started, it's set before the wait groups are done
+	PreparationErr *error
+	// WorldProfile is written to the server.properties of the world before the container starts
+	// Its name is saved in the container, so prepared containers can be matched with McContainerSearchConfig.WorldProfile
+	WorldProfile WorldProfile
 }
 
 // McContainerSearchConfig
 // Default Status is Prepared
 // IF Status is enums.Running ready prepared container are NOT returned
+// WorldProfile is the name of the world profile of prepared containers, empty for the default world.
+// Prepared containers of every profile are returned if AnyWorldProfile is true
 type McContainerSearchConfig struct {
-	McVersion string
-	Status    enums.ServerStatus
-	RamSizeMB int
+	McVersion       string
+	Status          enums.ServerStatus
+	RamSizeMB       int
+	WorldProfile    string
+	AnyWorldProfile bool
 }
 
 type McContainerResourceStats struct {
@@ -0,0 +1,46 @@
+package models
+
+// OnlinePlayers is parsed from the output of the `list` command
+type OnlinePlayers struct {
+	Count      int      `json:"count"`
+	MaxPlayers int      `json:"max_players"`
+	Players    []string `json:"players"`
+}
+
+// The entries of the player lists use the format of the json files of the mc server
+
+// WhitelistEntry is an entry of whitelist.json
+type WhitelistEntry struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
+// OpEntry is an entry of ops.json
+type OpEntry struct {
+	UUID                string `json:"uuid"`
+	Name                string `json:"name"`
+	Level               int    `json:"level"`
+	BypassesPlayerLimit bool   `json:"bypassesPlayerLimit"`
+}
+
+// BanEntry is an entry of banned-players.json, the times use the format `2006-01-02 15:04:05 -0700`
+type BanEntry struct {
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+func (entry WhitelistEntry) PlayerName() string {
+	return entry.Name
+}
+
+func (entry OpEntry) PlayerName() string {
+	return entry.Name
+}
+
+func (entry BanEntry) PlayerName() string {
+	return entry.Name
+}
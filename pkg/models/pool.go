@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// PoolTarget defines how many prepared containers with a specific mc version, ram size and world profile should be kept ready
+// An empty Profile stands for the default world
+type PoolTarget struct {
+	McVersion string `json:"mc_version"`
+	RamSizeMB int    `json:"ram_size_mb"`
+	Profile   string `json:"profile,omitempty" gorm:"not null;default:''"`
+	Count     int    `json:"count"`
+}
+
+// PoolStats shows how many start requests could be served instantly by a prepared container
+type PoolStats struct {
+	WindowHours int                `json:"window_hours"`
+	Requests    int                `json:"requests"`
+	Hits        int                `json:"hits"`
+	Misses      int                `json:"misses"`
+	HitRatio    float64            `json:"hit_ratio"`
+	Versions    []PoolVersionStats `json:"versions"`
+}
+
+type PoolVersionStats struct {
+	McVersion   string    `json:"mc_version"`
+	RamSizeMB   int       `json:"ram_size_mb"`
+	Profile     string    `json:"profile,omitempty"`
+	Requests    int       `json:"requests"`
+	Hits        int       `json:"hits"`
+	Misses      int       `json:"misses"`
+	LastRequest time.Time `json:"last_request"`
+}
@@ -0,0 +1,17 @@
+package models
+
+// HostResources describes the resources of the host and how much memory has been allocated to mc server containers
+// MemoryCapacityMB is the memory which can be allocated to mc server containers in total
+// MemoryPreparedMB is the part of MemoryAllocatedMB which is used by prepared containers and can be freed on demand
+// MemoryReservedMB is the part of MemoryAllocatedMB which has been admitted to servers that are being started right now
+type HostResources struct {
+	CPUs              int `json:"cpus"`
+	MemoryTotalMB     int `json:"memory_total_mb"`
+	MemoryAvailableMB int `json:"memory_available_mb"`
+	MemoryCapacityMB  int `json:"memory_capacity_mb"`
+	MemoryAllocatedMB int `json:"memory_allocated_mb"`
+	MemoryPreparedMB  int `json:"memory_prepared_mb"`
+	MemoryReservedMB  int `json:"memory_reserved_mb"`
+	DiskTotalMB       int `json:"disk_total_mb"`
+	DiskFreeMB        int `json:"disk_free_mb"`
+}
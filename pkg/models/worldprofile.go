@@ -0,0 +1,47 @@
+package models
+
+import "gorm.io/gorm"
+
+// WorldGenerationProfile contains the settings which have to be decided before a world is generated
+// The zero value is the default world of the image
+type WorldGenerationProfile struct {
+	Seed      string `json:"seed,omitempty"`
+	LevelType string `json:"level_type,omitempty"`
+	GameMode  string `json:"gamemode,omitempty"`
+}
+
+func (profile WorldGenerationProfile) IsDefault() bool {
+	return profile == WorldGenerationProfile{}
+}
+
+// ServerProperties Returns the server.properties values of the profile, unset settings are left out
+func (profile WorldGenerationProfile) ServerProperties() map[string]string {
+	properties := map[string]string{}
+	if profile.Seed != "" {
+		properties["level-seed"] = profile.Seed
+	}
+	if profile.LevelType != "" {
+		properties["level-type"] = profile.LevelType
+	}
+	if profile.GameMode != "" {
+		properties["gamemode"] = profile.GameMode
+	}
+	return properties
+}
+
+// WorldProfile is a world generation profile. Named profiles can be prepared by the pool,
+// a profile without name contains the custom settings of a single start request
+type WorldProfile struct {
+	Name string `json:"name"`
+	WorldGenerationProfile
+}
+
+// CanBePrepared Returns false for custom settings, a prepared container can't have a world generated with them
+func (profile WorldProfile) CanBePrepared() bool {
+	return profile.Name != "" || profile.IsDefault()
+}
+
+type DBWorldProfile struct {
+	gorm.Model
+	WorldProfile
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"github.com/instantmc/server/pkg/enums"
+	"time"
+)
+
+// WorldUploadProgress is the state of the last world upload of a server
+// TotalBytes is 0 if the client didn't send a Content-Length, Percent is only known with it
+type WorldUploadProgress struct {
+	Stage         enums.WorldUploadStage `json:"stage"`
+	ReceivedBytes int64                  `json:"received_bytes"`
+	TotalBytes    int64                  `json:"total_bytes"`
+	Percent       int                    `json:"percent"`
+	Error         string                 `json:"error,omitempty"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression with the fields minute, hour, day of month, month and day of week
+type CronSchedule struct {
+	minutes, hours, daysOfMonth, months, daysOfWeek map[int]bool
+	// restricted days are combined with OR like in the classic cron, fields starting with `*` like `*/2` aren't restricted
+	daysOfMonthRestricted, daysOfWeekRestricted bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseCron Parses a cron expression like `30 3 * * 1-5` or one of the macros @hourly, @daily, @weekly and @monthly
+// Every field supports `*`, single values, ranges (`1-5`), steps (`*/15`, `0-30/10`) and lists (`1,15`). Sunday is 0 or 7
+func ParseCron(expression string) (CronSchedule, error) {
+	if macro, ok := cronMacros[strings.TrimSpace(expression)]; ok {
+		expression = macro
+	}
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return CronSchedule{}, fmt.Errorf("a cron expression needs 5 fields, got %d", len(fields))
+	}
+	var schedule CronSchedule
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return CronSchedule{}, fmt.Errorf("minute: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return CronSchedule{}, fmt.Errorf("hour: %w", err)
+	}
+	if schedule.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return CronSchedule{}, fmt.Errorf("day of month: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return CronSchedule{}, fmt.Errorf("month: %w", err)
+	}
+	if schedule.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return CronSchedule{}, fmt.Errorf("day of week: %w", err)
+	}
+	if schedule.daysOfWeek[7] {
+		schedule.daysOfWeek[0] = true
+	}
+	schedule.daysOfMonthRestricted = !strings.HasPrefix(fields[2], "*")
+	schedule.daysOfWeekRestricted = !strings.HasPrefix(fields[4], "*")
+	return schedule, nil
+}
+
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, part := range strings.Split(field, ",") {
+		valueRange, stepRaw, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepRaw); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step %q", stepRaw)
+			}
+		}
+		start, end := min, max
+		if valueRange != "*" {
+			startRaw, endRaw, isRange := strings.Cut(valueRange, "-")
+			var err error
+			if start, err = strconv.Atoi(startRaw); err != nil {
+				return nil, fmt.Errorf("invalid value %q", startRaw)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endRaw); err != nil {
+					return nil, fmt.Errorf("invalid value %q", endRaw)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is not within %d-%d", part, min, max)
+		}
+		for value := start; value <= end; value += step {
+			values[value] = true
+		}
+	}
+	return values, nil
+}
+
+// Matches Returns true if the schedule is due in the minute of t
+func (schedule CronSchedule) Matches(t time.Time) bool {
+	if !schedule.minutes[t.Minute()] || !schedule.hours[t.Hour()] || !schedule.months[int(t.Month())] {
+		return false
+	}
+	dayOfMonth := schedule.daysOfMonth[t.Day()]
+	dayOfWeek := schedule.daysOfWeek[int(t.Weekday())]
+	if schedule.daysOfMonthRestricted && schedule.daysOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	// Monday, 2023-03-06 03:30
+	monday := time.Date(2023, 3, 6, 3, 30, 0, 0, time.UTC)
+	cases := []struct {
+		expression string
+		t          time.Time
+		matches    bool
+	}{
+		{"30 3 * * *", monday, true},
+		{"30 3 * * *", monday.Add(time.Minute), false},
+		{"*/15 * * * *", monday, true},
+		{"0-20/10 * * * *", monday, false},
+		{"30 3 * * 1-5", monday, true},
+		{"30 3 * * 0,6", monday, false},
+		{"30 3 * * 7", monday.AddDate(0, 0, 6), true},
+		{"30 3 1 * 1", monday, true},
+		{"30 3 1 * *", monday, false},
+		{"30 3 */2 * 1", monday, false},
+		{"30 3 */2 * 1", monday.AddDate(0, 0, 7), true},
+		{"30 3 1 * */2", monday, false},
+		{"30 3 6 * */2", monday, false},
+		{"30 3 6 * 1/2", monday, true},
+		{"@daily", time.Date(2023, 3, 6, 0, 0, 0, 0, time.UTC), true},
+		{"@weekly", time.Date(2023, 3, 5, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, c := range cases {
+		schedule, err := ParseCron(c.expression)
+		if err != nil {
+			t.Fatalf("Couldn't parse %q: %s", c.expression, err)
+		}
+		if matches := schedule.Matches(c.t); matches != c.matches {
+			t.Errorf("%q matches %s: %t, expected %t", c.expression, c.t, matches, c.matches)
+		}
+	}
+
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expression); err == nil {
+			t.Errorf("%q should be invalid", expression)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareMcVersions Returns -1 if a is older than b, 1 if a is newer than b and 0 if both are equal
+// Missing parts count as 0, e.g. 1.19 equals 1.19.0
+func CompareMcVersions(a string, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		partA, partB := mcVersionPart(partsA, i), mcVersionPart(partsB, i)
+		if partA < partB {
+			return -1
+		}
+		if partA > partB {
+			return 1
+		}
+	}
+	return 0
+}
+
+func mcVersionPart(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	part, _ := strconv.Atoi(parts[i])
+	return part
+}
@@ -0,0 +1,20 @@
+package utils
+
+import "testing"
+
+func TestCompareMcVersions(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.19", "1.19.0", 0},
+		{"1.19.4", "1.19", 1},
+		{"1.9.4", "1.14", -1},
+		{"1.20.1", "1.7.10", 1},
+	}
+	for _, c := range cases {
+		if result := CompareMcVersions(c.a, c.b); result != c.expected {
+			t.Errorf("CompareMcVersions(%s, %s) = %d, expected %d", c.a, c.b, result, c.expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/instantmc/server/pkg/config"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+// argon2id parameters, see https://datatracker.ietf.org/doc/html/rfc9106#section-4
+const (
+	argon2Time      = 1
+	argon2MemoryKiB = 64 * 1024
+	argon2Threads   = 4
+	argon2KeyLength = 32
+	argon2SaltBytes = 16
+
+	argon2Prefix = "$argon2id$"
+)
+
+// HashPassword Hashes the password with argon2id and a random salt
+// The result has the format `$argon2id$v=19$m=65536,t=1,p=4$<base64 salt>$<base64 hash>`
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, argon2Time, argon2MemoryKiB, argon2Threads, argon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2Prefix, argon2.Version, argon2MemoryKiB, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// VerifyPassword Checks the password against a hash created by HashPassword or a legacy unsalted SHA256 hash
+// needsRehash is true if the password matches but the hash should be replaced by a new one of HashPassword
+func VerifyPassword(password string, passwordHash string) (matches bool, needsRehash bool) {
+	if !strings.HasPrefix(passwordHash, argon2Prefix) {
+		// passwords used to be saved as unsalted SHA256 hashes
+		matches = subtle.ConstantTimeCompare([]byte(SHA256([]byte(password))), []byte(passwordHash)) == 1
+		return matches, matches
+	}
+
+	parts := strings.Split(passwordHash, "$")
+	// "", "argon2id", "v=19", "m=65536,t=1,p=4", salt, hash
+	if len(parts) != 6 {
+		return false, false
+	}
+	var version int
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+	expectedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(expectedHash)))
+	matches = subtle.ConstantTimeCompare(hash, expectedHash) == 1
+	needsRehash = memory != argon2MemoryKiB || time != argon2Time || threads != argon2Threads || len(expectedHash) != argon2KeyLength
+	return matches, matches && needsRehash
+}
+
+// CheckPasswordPolicy Returns an error describing why the password isn't allowed
+func CheckPasswordPolicy(password string, username string) error {
+	if len(password) < config.MinimumPasswordLength {
+		return fmt.Errorf("the password must be at least %d characters long", config.MinimumPasswordLength)
+	}
+	if strings.EqualFold(password, username) {
+		return errors.New("the password must not be the username")
+	}
+	for _, forbidden := range config.ForbiddenPasswords {
+		if strings.EqualFold(password, forbidden) {
+			return errors.New("the password is too common")
+		}
+	}
+	return nil
+}
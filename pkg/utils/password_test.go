@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		t.Errorf("Hash %s doesn't start with %s", hash, argon2Prefix)
+	}
+	otherHash, _ := HashPassword("correct horse battery staple")
+	if hash == otherHash {
+		t.Errorf("Hashes of the same password must differ because of the salt")
+	}
+
+	if matches, needsRehash := VerifyPassword("correct horse battery staple", hash); !matches || needsRehash {
+		t.Errorf("Password should match without rehash (matches: %t, needsRehash: %t)", matches, needsRehash)
+	}
+	if matches, _ := VerifyPassword("wrong password", hash); matches {
+		t.Errorf("Wrong password shouldn't match")
+	}
+}
+
+func TestVerifyLegacyPassword(t *testing.T) {
+	legacyHash := SHA256([]byte("admin"))
+	if matches, needsRehash := VerifyPassword("admin", legacyHash); !matches || !needsRehash {
+		t.Errorf("Legacy password should match and need a rehash (matches: %t, needsRehash: %t)", matches, needsRehash)
+	}
+	if matches, needsRehash := VerifyPassword("Admin", legacyHash); matches || needsRehash {
+		t.Errorf("Wrong legacy password shouldn't match")
+	}
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	for _, password := range []string{"admin", "short", "minecraft", "stevesteve1"} {
+		if CheckPasswordPolicy(password, "stevesteve1") == nil {
+			t.Errorf("Password %s should not be allowed", password)
+		}
+	}
+	if err := CheckPasswordPolicy("correct horse battery staple", "steve"); err != nil {
+		t.Errorf("Password should be allowed: %s", err)
+	}
+}
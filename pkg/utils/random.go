@@ -1,6 +1,8 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"time"
@@ -17,3 +19,12 @@ func RandomString(length int) string {
 	rand.Read(b)
 	return fmt.Sprintf("%x", b)[2 : length+2]
 }
+
+// SecureRandomString Returns a random hex string of given length generated by a cryptographically secure source
+func SecureRandomString(length int) (string, error) {
+	b := make([]byte, (length+1)/2)
+	if _, err := cryptorand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b)[:length], nil
+}